@@ -0,0 +1,660 @@
+// Package config loads the service configuration from defaults, an optional
+// YAML or TOML file, environment variables and command-line flags, in that
+// order of increasing precedence.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes every environment variable the service reads.
+const EnvPrefix = "USER_SERVICE_"
+
+// Config is the complete service configuration.
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server"`
+	Database   DatabaseConfig   `yaml:"database" toml:"database"`
+	Cache      CacheConfig      `yaml:"cache" toml:"cache"`
+	Loader     LoaderConfig     `yaml:"loader" toml:"loader"`
+	Batch      BatchConfig      `yaml:"batch" toml:"batch"`
+	Users      UsersConfig      `yaml:"users" toml:"users"`
+	Pagination PaginationConfig `yaml:"pagination" toml:"pagination"`
+	Search     SearchConfig     `yaml:"search" toml:"search"`
+	Log        LogConfig        `yaml:"log" toml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	Addr         string   `yaml:"addr" toml:"addr"`
+	GinMode      string   `yaml:"gin_mode" toml:"gin_mode"`
+	ReadTimeout  Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+
+	// ShutdownTimeout bounds how long in-flight requests may run after a
+	// shutdown signal before they are cut off.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// UpgradeTimeout bounds how long a re-executed instance may take to start
+	// serving before the upgrade is abandoned.
+	UpgradeTimeout Duration `yaml:"upgrade_timeout" toml:"upgrade_timeout"`
+
+	// RequestTimeout bounds how long a request may run; 0 means no limit.
+	// RouteTimeouts overrides it per route, keyed by method and route
+	// template such as "POST /users/bulk".
+	RequestTimeout Duration            `yaml:"request_timeout" toml:"request_timeout"`
+	RouteTimeouts  map[string]Duration `yaml:"route_timeouts" toml:"route_timeouts"`
+}
+
+// DatabaseConfig configures the SQLite database and its connection pool.
+// Zero pool values keep the database/sql defaults.
+type DatabaseConfig struct {
+	Path            string   `yaml:"path" toml:"path"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+
+	JournalMode string   `yaml:"journal_mode" toml:"journal_mode"`
+	Synchronous string   `yaml:"synchronous" toml:"synchronous"`
+	BusyTimeout Duration `yaml:"busy_timeout" toml:"busy_timeout"`
+	ForeignKeys bool     `yaml:"foreign_keys" toml:"foreign_keys"`
+	// SingleWriter sends writes through one connection and reads through a
+	// separate read-only pool, which the pool settings above then size.
+	SingleWriter bool `yaml:"single_writer" toml:"single_writer"`
+	// CreateBatchSize is how many concurrent user creations may be committed
+	// in one transaction; 0 or 1 commits each on its own. CreateBatchWait is
+	// how long the first of a batch waits for others to join it.
+	CreateBatchSize int      `yaml:"create_batch_size" toml:"create_batch_size"`
+	CreateBatchWait Duration `yaml:"create_batch_wait" toml:"create_batch_wait"`
+	// ParallelReads is how many chunks of a large ID lookup are queried at
+	// once.
+	ParallelReads int `yaml:"parallel_reads" toml:"parallel_reads"`
+
+	// Migrate is what startup does about pending schema migrations: "auto"
+	// applies them, "check" refuses to start until "migrate up" has run.
+	Migrate string `yaml:"migrate" toml:"migrate"`
+}
+
+// CacheConfig configures the in-process cache of users looked up by ID.
+type CacheConfig struct {
+	// Size is how many IDs are cached; 0 disables the cache.
+	Size int      `yaml:"size" toml:"size"`
+	TTL  Duration `yaml:"ttl" toml:"ttl"`
+	// NegativeTTL is how long an ID with no user is remembered as missing.
+	NegativeTTL Duration `yaml:"negative_ttl" toml:"negative_ttl"`
+}
+
+// LoaderConfig configures how concurrent lookups of single users are merged.
+type LoaderConfig struct {
+	// BatchSize is how many IDs one merged query loads; 0 disables merging.
+	BatchSize int `yaml:"batch_size" toml:"batch_size"`
+	// BatchWait is how long the first lookup of a batch waits for others.
+	BatchWait Duration `yaml:"batch_wait" toml:"batch_wait"`
+}
+
+// UsersConfig configures the lifecycle of user records.
+type UsersConfig struct {
+	// Retention is how long a soft-deleted user can be restored before
+	// POST /users/purge removes it.
+	Retention Duration `yaml:"retention" toml:"retention"`
+}
+
+// BatchConfig limits the size of batch requests.
+type BatchConfig struct {
+	// MaxIDs is the most IDs POST /users/batch accepts.
+	MaxIDs int `yaml:"max_ids" toml:"max_ids"`
+	// MaxBulkUsers is the most users POST /users/bulk accepts.
+	MaxBulkUsers int `yaml:"max_bulk_users" toml:"max_bulk_users"`
+}
+
+// PaginationConfig configures how listings are paged.
+type PaginationConfig struct {
+	// CursorSecret signs pagination cursors. Every instance behind the same
+	// clients needs the same secret; without one each process signs with a
+	// random key, and cursors stop working when it restarts.
+	CursorSecret Secret `yaml:"cursor_secret" toml:"cursor_secret"`
+	// MaxPageSize is the largest page_size a listing accepts.
+	MaxPageSize int `yaml:"max_page_size" toml:"max_page_size"`
+}
+
+// SearchConfig configures name search.
+type SearchConfig struct {
+	// FuzzyThreshold is the lowest trigram similarity, between 0 and 1, a
+	// fuzzy search hit may have.
+	FuzzyThreshold float64 `yaml:"fuzzy_threshold" toml:"fuzzy_threshold"`
+}
+
+// LogConfig configures logging.
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+	// Redact lists attribute and query parameter names whose values are
+	// replaced in log records, matched case-insensitively.
+	Redact []string `yaml:"redact" toml:"redact"`
+}
+
+// TracingConfig configures distributed tracing.
+type TracingConfig struct {
+	// Output is where finished spans are written as OTLP JSON: a file path,
+	// "stdout", or empty to disable tracing.
+	Output string `yaml:"output" toml:"output"`
+	// SampleRatio is the fraction of new traces recorded. Traces continued
+	// from a caller follow the caller's decision.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Default returns the configuration used when nothing overrides it.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:         ":6001",
+			GinMode:      "debug",
+			ReadTimeout:  Duration(15 * time.Second),
+			WriteTimeout: Duration(30 * time.Second),
+			IdleTimeout:  Duration(60 * time.Second),
+
+			ShutdownTimeout: Duration(30 * time.Second),
+			UpgradeTimeout:  Duration(30 * time.Second),
+
+			RequestTimeout: Duration(10 * time.Second),
+			RouteTimeouts: map[string]Duration{
+				"POST /users/bulk":  Duration(20 * time.Second),
+				"POST /users/purge": Duration(20 * time.Second),
+			},
+		},
+		Database: DatabaseConfig{
+			Path:         "user.db",
+			MaxIdleConns: 2,
+			JournalMode:  "wal",
+			Synchronous:  "normal",
+			BusyTimeout:  Duration(5 * time.Second),
+			ForeignKeys:  true,
+			SingleWriter: true,
+			Migrate:      "auto",
+
+			CreateBatchSize: 64,
+			CreateBatchWait: Duration(2 * time.Millisecond),
+			ParallelReads:   1,
+		},
+		Cache: CacheConfig{
+			Size:        10000,
+			TTL:         Duration(30 * time.Second),
+			NegativeTTL: Duration(5 * time.Second),
+		},
+		Loader: LoaderConfig{
+			BatchSize: 100,
+			BatchWait: Duration(time.Millisecond),
+		},
+		Batch: BatchConfig{
+			MaxIDs:       1000,
+			MaxBulkUsers: 1000,
+		},
+		Users: UsersConfig{
+			Retention: Duration(30 * 24 * time.Hour),
+		},
+		Pagination: PaginationConfig{
+			MaxPageSize: 100,
+		},
+		Search: SearchConfig{
+			FuzzyThreshold: 0.3,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Redact: []string{"authorization", "cookie", "password", "secret", "token"},
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Duration is a time.Duration written as a string such as "15s" in files,
+// environment variables and flags.
+type Duration time.Duration
+
+// Std returns d as a time.Duration.
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, want a value such as \"30s\"", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Set implements flag.Value.
+func (d *Duration) Set(s string) error { return d.UnmarshalText([]byte(s)) }
+
+// Secret is a string that is never printed: Write shows a set secret as
+// "[REDACTED]".
+type Secret string
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Secret) MarshalText() ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return []byte("[REDACTED]"), nil
+}
+
+// Options are command-line switches that are not configuration values.
+type Options struct {
+	// File is the configuration file that was loaded, if any.
+	File string
+	// PrintConfig asks the caller to print the effective configuration and exit.
+	PrintConfig bool
+}
+
+// setting binds one configuration value to its file key, environment
+// variable and flag.
+type setting struct {
+	key   string
+	usage string
+	value flag.Value
+}
+
+// env is the environment variable for the setting, e.g. USER_SERVICE_SERVER_ADDR.
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_").Replace(s.key))
+}
+
+// flag is the command-line flag for the setting, e.g. -server-addr.
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings lists every configurable value of c.
+func settings(c *Config) []setting {
+	return []setting{
+		{"server.addr", "listen address (host:port)", (*stringValue)(&c.Server.Addr)},
+		{"server.gin_mode", "gin mode: debug, release or test", (*stringValue)(&c.Server.GinMode)},
+		{"server.read_timeout", "maximum duration for reading a request", &c.Server.ReadTimeout},
+		{"server.write_timeout", "maximum duration for writing a response", &c.Server.WriteTimeout},
+		{"server.idle_timeout", "how long keep-alive connections stay idle", &c.Server.IdleTimeout},
+		{"server.shutdown_timeout", "how long to drain in-flight requests on shutdown", &c.Server.ShutdownTimeout},
+		{"server.upgrade_timeout", "how long a re-executed instance may take to start serving", &c.Server.UpgradeTimeout},
+		{"server.request_timeout", "how long a request may run (0 = no limit)", &c.Server.RequestTimeout},
+		{"server.route_timeouts", `comma-separated per-route timeouts such as "POST /users/bulk=20s"`, (*durationMapValue)(&c.Server.RouteTimeouts)},
+		{"database.path", "SQLite database file", (*stringValue)(&c.Database.Path)},
+		{"database.max_open_conns", "maximum open connections (0 = unlimited)", (*intValue)(&c.Database.MaxOpenConns)},
+		{"database.max_idle_conns", "maximum idle connections", (*intValue)(&c.Database.MaxIdleConns)},
+		{"database.conn_max_lifetime", "maximum connection lifetime (0 = forever)", &c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", "maximum connection idle time (0 = forever)", &c.Database.ConnMaxIdleTime},
+		{"database.journal_mode", "SQLite journal mode: delete, truncate, persist, memory, wal or off", (*stringValue)(&c.Database.JournalMode)},
+		{"database.synchronous", "SQLite synchronous level: off, normal, full or extra", (*stringValue)(&c.Database.Synchronous)},
+		{"database.busy_timeout", "how long a statement waits for a locked database", &c.Database.BusyTimeout},
+		{"database.foreign_keys", "enforce foreign key constraints", (*boolValue)(&c.Database.ForeignKeys)},
+		{"database.single_writer", "one write connection plus a read-only pool", (*boolValue)(&c.Database.SingleWriter)},
+		{"database.create_batch_size", "user creations committed together (0 or 1 = no batching)", (*intValue)(&c.Database.CreateBatchSize)},
+		{"database.create_batch_wait", "how long a user creation waits for others to batch with", &c.Database.CreateBatchWait},
+		{"database.parallel_reads", "chunks of a large ID lookup queried at once", (*intValue)(&c.Database.ParallelReads)},
+		{"database.migrate", "pending migrations at startup: auto (apply) or check (refuse to start)", (*stringValue)(&c.Database.Migrate)},
+		{"cache.size", "users cached by ID (0 = no cache)", (*intValue)(&c.Cache.Size)},
+		{"cache.ttl", "how long a cached user is served", &c.Cache.TTL},
+		{"cache.negative_ttl", "how long an ID with no user is remembered as missing", &c.Cache.NegativeTTL},
+		{"loader.batch_size", "user lookups merged into one query (0 = no merging)", (*intValue)(&c.Loader.BatchSize)},
+		{"loader.batch_wait", "how long a user lookup waits for others to merge with", &c.Loader.BatchWait},
+		{"users.retention", "how long a deleted user stays restorable", &c.Users.Retention},
+		{"batch.max_ids", "most IDs accepted by POST /users/batch", (*intValue)(&c.Batch.MaxIDs)},
+		{"batch.max_bulk_users", "most users accepted by POST /users/bulk", (*intValue)(&c.Batch.MaxBulkUsers)},
+		{"pagination.cursor_secret", "key signing pagination cursors, shared by every instance", (*secretValue)(&c.Pagination.CursorSecret)},
+		{"pagination.max_page_size", "largest page_size a listing accepts", (*intValue)(&c.Pagination.MaxPageSize)},
+		{"search.fuzzy_threshold", "lowest similarity of a fuzzy search hit (0 to 1)", (*floatValue)(&c.Search.FuzzyThreshold)},
+		{"log.level", "log level: debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"log.redact", "comma-separated names of values to redact from logs", (*listValue)(&c.Log.Redact)},
+		{"tracing.output", `span output: file path, "stdout", or empty to disable`, (*stringValue)(&c.Tracing.Output)},
+		{"tracing.sample_ratio", "fraction of new traces recorded (0 to 1)", (*floatValue)(&c.Tracing.SampleRatio)},
+	}
+}
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+// secretValue keeps the secret out of the usage text.
+type secretValue Secret
+
+func (v *secretValue) String() string     { return "" }
+func (v *secretValue) Set(s string) error { *v = secretValue(s); return nil }
+
+// listValue is a comma-separated list. An empty string sets an empty list.
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+func (v *listValue) Set(s string) error {
+	*v = []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+
+// durationMapValue is a comma-separated list of key=duration pairs. An empty
+// string sets an empty map.
+type durationMapValue map[string]Duration
+
+func (v *durationMapValue) String() string {
+	keys := make([]string, 0, len(*v))
+	for k := range *v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + (*v)[k].String()
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *durationMapValue) Set(s string) error {
+	m := make(map[string]Duration)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid pair %q, want key=duration", pair)
+		}
+		var d Duration
+		if err := d.Set(strings.TrimSpace(value)); err != nil {
+			return err
+		}
+		m[strings.TrimSpace(key)] = d
+	}
+	*v = m
+	return nil
+}
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+// Load builds the configuration from args (without the program name) and the
+// environment. The configuration file is named by -config or
+// USER_SERVICE_CONFIG. The result is validated before it is returned.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, Options, error) {
+	cfg := Default()
+	var opts Options
+
+	fs := flag.NewFlagSet("user-service", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.File, "config", "", "configuration file (.yaml, .yml or .toml)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	// Flags are recorded first and applied last so they win over the file
+	// and the environment.
+	flagged := make(map[string]string)
+	for _, s := range settings(&cfg) {
+		name := s.flag()
+		fs.Func(name, s.usage, func(v string) error {
+			flagged[name] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, opts, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if opts.File == "" {
+		opts.File, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if opts.File != "" {
+		if err := loadFile(opts.File, &cfg); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings(&cfg) {
+		if v, ok := lookupEnv(s.env()); ok {
+			if err := s.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env(), err))
+			}
+		}
+	}
+	for _, s := range settings(&cfg) {
+		if v, ok := flagged[s.flag()]; ok {
+			if err := s.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.flag(), err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return cfg, opts, err
+	}
+
+	return cfg, opts, cfg.Validate()
+}
+
+// Usage writes the command-line help, including every flag and the
+// environment variable that sets the same value.
+func Usage(w io.Writer) {
+	cfg := Default()
+	fmt.Fprintf(w, "Usage: user-service [flags]\n")
+	fmt.Fprintf(w, "       user-service migrate up|down|status [flags]\n\n")
+	fmt.Fprintf(w, "  -config string\n\tconfiguration file (.yaml, .yml or .toml); env %sCONFIG\n", EnvPrefix)
+	fmt.Fprintf(w, "  -print-config\n\tprint the effective configuration and exit\n")
+	for _, s := range settings(&cfg) {
+		fmt.Fprintf(w, "  -%s\n\t%s (default %q); env %s\n", s.flag(), s.usage, s.value.String(), s.env())
+	}
+}
+
+// loadFile decodes a YAML or TOML file over cfg, chosen by file extension.
+// Unknown keys are rejected so typos do not silently fall back to defaults.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			var strict *toml.StrictMissingError
+			if errors.As(err, &strict) {
+				keys := make([]string, len(strict.Errors))
+				for i, e := range strict.Errors {
+					keys[i] = strings.Join(e.Key(), ".")
+				}
+				return fmt.Errorf("config file %s: unknown keys: %s", path, strings.Join(keys, ", "))
+			}
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, want .yaml, .yml or .toml", path, ext)
+	}
+	return nil
+}
+
+// minSecretLength is the shortest pagination.cursor_secret accepted.
+const minSecretLength = 32
+
+// Validate reports every invalid value at once, each prefixed by its key.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+
+	_, port, err := net.SplitHostPort(c.Server.Addr)
+	check(err == nil && port != "", "server.addr", "must be host:port, got %q", c.Server.Addr)
+	check(oneOf(c.Server.GinMode, "debug", "release", "test"), "server.gin_mode",
+		"must be debug, release or test, got %q", c.Server.GinMode)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.Server.UpgradeTimeout > 0, "server.upgrade_timeout", "must be positive")
+	check(c.Server.RequestTimeout >= 0, "server.request_timeout", "must not be negative")
+	routes := make([]string, 0, len(c.Server.RouteTimeouts))
+	for route := range c.Server.RouteTimeouts {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		key := "server.route_timeouts." + route
+		method, path, _ := strings.Cut(route, " ")
+		check(method != "" && method == strings.ToUpper(method) && strings.HasPrefix(path, "/"), key,
+			"must be a method and route such as \"POST /users/bulk\"")
+		check(c.Server.RouteTimeouts[route] >= 0, key, "must not be negative")
+	}
+
+	check(c.Database.Path != "", "database.path", "must not be empty")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns", "must not exceed database.max_open_conns (%d)", c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative")
+	check(oneOf(strings.ToLower(c.Database.JournalMode), "delete", "truncate", "persist", "memory", "wal", "off"),
+		"database.journal_mode", "must be delete, truncate, persist, memory, wal or off, got %q", c.Database.JournalMode)
+	check(oneOf(strings.ToLower(c.Database.Synchronous), "off", "normal", "full", "extra"),
+		"database.synchronous", "must be off, normal, full or extra, got %q", c.Database.Synchronous)
+	check(c.Database.BusyTimeout >= 0, "database.busy_timeout", "must not be negative")
+	check(!c.Database.SingleWriter || strings.EqualFold(c.Database.JournalMode, "wal"), "database.single_writer",
+		"needs database.journal_mode wal, got %q", c.Database.JournalMode)
+	check(c.Database.CreateBatchSize >= 0, "database.create_batch_size", "must not be negative")
+	check(c.Database.CreateBatchWait >= 0, "database.create_batch_wait", "must not be negative")
+	check(c.Database.ParallelReads > 0, "database.parallel_reads", "must be positive")
+	check(oneOf(c.Database.Migrate, "auto", "check"), "database.migrate",
+		"must be auto or check, got %q", c.Database.Migrate)
+
+	check(c.Cache.Size >= 0, "cache.size", "must not be negative")
+	check(c.Cache.TTL >= 0, "cache.ttl", "must not be negative")
+	check(c.Cache.NegativeTTL >= 0, "cache.negative_ttl", "must not be negative")
+
+	check(c.Loader.BatchSize >= 0, "loader.batch_size", "must not be negative")
+	check(c.Loader.BatchWait >= 0, "loader.batch_wait", "must not be negative")
+
+	check(c.Users.Retention > 0, "users.retention", "must be positive")
+
+	check(c.Batch.MaxIDs > 0, "batch.max_ids", "must be positive")
+	check(c.Batch.MaxBulkUsers > 0, "batch.max_bulk_users", "must be positive")
+
+	check(c.Pagination.CursorSecret == "" || len(c.Pagination.CursorSecret) >= minSecretLength,
+		"pagination.cursor_secret", "must be at least %d characters", minSecretLength)
+	check(c.Pagination.MaxPageSize > 0, "pagination.max_page_size", "must be positive")
+
+	check(c.Search.FuzzyThreshold > 0 && c.Search.FuzzyThreshold <= 1, "search.fuzzy_threshold",
+		"must be above 0 and at most 1, got %g", c.Search.FuzzyThreshold)
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level",
+		"must be debug, info, warn or error, got %q", c.Log.Level)
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errs: errs}
+}
+
+// ValidationError lists every problem found by Validate.
+type ValidationError struct {
+	Errs []error
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		lines[i] = "  " + err.Error()
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error { return e.Errs }
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints c as YAML, in the same shape the configuration file uses.
+func (c Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
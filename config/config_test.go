@@ -0,0 +1,278 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"user-service/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// env returns a lookup function over a fixed set of variables.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, opts, err := config.Load(nil, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Equal(t, config.Options{}, opts)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  addr: ":7000"
+  gin_mode: release
+  read_timeout: 5s
+  route_timeouts:
+    GET /users/search: 3s
+database:
+  path: file.db
+  max_open_conns: 8
+log:
+  level: warn
+`)
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, cfg config.Config)
+	}{
+		{
+			name: "file over defaults",
+			args: []string{"-config", file},
+			check: func(t *testing.T, cfg config.Config) {
+				assert.Equal(t, ":7000", cfg.Server.Addr)
+				assert.Equal(t, "release", cfg.Server.GinMode)
+				assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout.Std())
+				assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout.Std())
+				assert.Equal(t, 8, cfg.Database.MaxOpenConns)
+				assert.Equal(t, 2, cfg.Database.MaxIdleConns)
+				assert.Equal(t, 3*time.Second, cfg.Server.RouteTimeouts["GET /users/search"].Std())
+				assert.Equal(t, 20*time.Second, cfg.Server.RouteTimeouts["POST /users/bulk"].Std())
+			},
+		},
+		{
+			name: "env over file",
+			args: []string{"-config", file},
+			env: map[string]string{
+				"USER_SERVICE_SERVER_ADDR":                ":7100",
+				"USER_SERVICE_DATABASE_MAX_OPEN_CONNS":    "4",
+				"USER_SERVICE_SERVER_READ_TIMEOUT":        "1m",
+				"USER_SERVICE_DATABASE_CONN_MAX_LIFETIME": "1h",
+				"USER_SERVICE_LOG_REDACT":                 "ssn, email,,",
+				"USER_SERVICE_SERVER_ROUTE_TIMEOUTS":      "POST /users = 2s, GET /users/:id=500ms",
+			},
+			check: func(t *testing.T, cfg config.Config) {
+				assert.Equal(t, ":7100", cfg.Server.Addr)
+				assert.Equal(t, 4, cfg.Database.MaxOpenConns)
+				assert.Equal(t, time.Minute, cfg.Server.ReadTimeout.Std())
+				assert.Equal(t, time.Hour, cfg.Database.ConnMaxLifetime.Std())
+				assert.Equal(t, []string{"ssn", "email"}, cfg.Log.Redact)
+				assert.Equal(t, map[string]config.Duration{
+					"POST /users":    config.Duration(2 * time.Second),
+					"GET /users/:id": config.Duration(500 * time.Millisecond),
+				}, cfg.Server.RouteTimeouts)
+				assert.Equal(t, "file.db", cfg.Database.Path)
+			},
+		},
+		{
+			name: "flags over env",
+			args: []string{"-server-addr", ":7200", "-log-level=debug"},
+			env: map[string]string{
+				"USER_SERVICE_CONFIG":      file,
+				"USER_SERVICE_SERVER_ADDR": ":7100",
+			},
+			check: func(t *testing.T, cfg config.Config) {
+				assert.Equal(t, ":7200", cfg.Server.Addr)
+				assert.Equal(t, "debug", cfg.Log.Level)
+				assert.Equal(t, "file.db", cfg.Database.Path)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := config.Load(tt.args, env(tt.env))
+			assert.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	file := writeFile(t, "config.toml", `
+[server]
+addr = "127.0.0.1:8080"
+idle_timeout = "2m"
+
+[server.route_timeouts]
+"POST /users/bulk" = "1m"
+
+[database]
+path = "/var/lib/users.db"
+max_idle_conns = 0
+`)
+
+	cfg, opts, err := config.Load([]string{"-config", file}, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, file, opts.File)
+	assert.Equal(t, "127.0.0.1:8080", cfg.Server.Addr)
+	assert.Equal(t, 2*time.Minute, cfg.Server.IdleTimeout.Std())
+	assert.Equal(t, time.Minute, cfg.Server.RouteTimeouts["POST /users/bulk"].Std())
+	assert.Equal(t, "/var/lib/users.db", cfg.Database.Path)
+	assert.Equal(t, 0, cfg.Database.MaxIdleConns)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    [2]string
+		args    []string
+		env     map[string]string
+		wantErr []string
+	}{
+		{
+			name:    "unknown yaml key",
+			file:    [2]string{"c.yaml", "server:\n  adr: \":1\"\n"},
+			wantErr: []string{"field adr not found"},
+		},
+		{
+			name:    "unknown toml key",
+			file:    [2]string{"c.toml", "[database]\npth = \"x\"\n"},
+			wantErr: []string{"database.pth"},
+		},
+		{
+			name:    "unsupported format",
+			file:    [2]string{"c.json", "{}"},
+			wantErr: []string{`unsupported format ".json"`},
+		},
+		{
+			name:    "bad env value",
+			env:     map[string]string{"USER_SERVICE_DATABASE_MAX_OPEN_CONNS": "many"},
+			wantErr: []string{`USER_SERVICE_DATABASE_MAX_OPEN_CONNS: invalid integer "many"`},
+		},
+		{
+			name:    "bad boolean",
+			env:     map[string]string{"USER_SERVICE_DATABASE_FOREIGN_KEYS": "maybe"},
+			wantErr: []string{`USER_SERVICE_DATABASE_FOREIGN_KEYS: invalid boolean "maybe"`},
+		},
+		{
+			name:    "bad flag value",
+			args:    []string{"-server-write-timeout", "soon"},
+			wantErr: []string{`-server-write-timeout: invalid duration "soon"`},
+		},
+		{
+			name:    "bad route timeout pair",
+			args:    []string{"-server-route-timeouts", "GET /users"},
+			wantErr: []string{`-server-route-timeouts: invalid pair "GET /users", want key=duration`},
+		},
+		{
+			name: "every invalid value is reported",
+			args: []string{"-server-addr", "6001", "-server-gin-mode", "prod", "-log-level", "trace",
+				"-database-max-open-conns", "1", "-database-max-idle-conns", "3", "-tracing-sample-ratio", "2",
+				"-database-migrate", "never", "-database-journal-mode", "delete", "-database-synchronous", "sometimes",
+				"-database-create-batch-size", "-1", "-cache-ttl", "-1s",
+				"-loader-batch-size", "-5", "-pagination-cursor-secret", "short",
+				"-users-retention", "0s", "-batch-max-ids", "0", "-batch-max-bulk-users", "-1", "-pagination-max-page-size", "0", "-search-fuzzy-threshold", "0", "-database-parallel-reads", "0", "-server-request-timeout", "-1s",
+				"-server-route-timeouts", "GET /users=-1s,users=5s"},
+			wantErr: []string{
+				"invalid configuration:",
+				`server.addr: must be host:port, got "6001"`,
+				`server.gin_mode: must be debug, release or test, got "prod"`,
+				"server.request_timeout: must not be negative",
+				"server.route_timeouts.GET /users: must not be negative",
+				`server.route_timeouts.users: must be a method and route such as "POST /users/bulk"`,
+				`log.level: must be debug, info, warn or error, got "trace"`,
+				"database.max_idle_conns: must not exceed database.max_open_conns (1)",
+				`database.synchronous: must be off, normal, full or extra, got "sometimes"`,
+				`database.single_writer: needs database.journal_mode wal, got "delete"`,
+				"database.create_batch_size: must not be negative",
+				`database.migrate: must be auto or check, got "never"`,
+				"cache.ttl: must not be negative",
+				"loader.batch_size: must not be negative",
+				"database.parallel_reads: must be positive",
+				"users.retention: must be positive",
+				"batch.max_ids: must be positive",
+				"batch.max_bulk_users: must be positive",
+				"pagination.cursor_secret: must be at least 32 characters",
+				"pagination.max_page_size: must be positive",
+				"search.fuzzy_threshold: must be above 0 and at most 1, got 0",
+				"tracing.sample_ratio: must be between 0 and 1, got 2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file[0] != "" {
+				args = append([]string{"-config", writeFile(t, tt.file[0], tt.file[1])}, args...)
+			}
+
+			_, _, err := config.Load(args, env(tt.env))
+			if assert.Error(t, err) {
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	_, _, err := config.Load([]string{"-h"}, env(nil))
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+
+	var usage bytes.Buffer
+	config.Usage(&usage)
+	assert.Contains(t, usage.String(), "-database-max-open-conns")
+	assert.Contains(t, usage.String(), "USER_SERVICE_DATABASE_MAX_OPEN_CONNS")
+}
+
+func TestConfig_WriteRedactsSecrets(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	cfg, _, err := config.Load(nil, env(map[string]string{"USER_SERVICE_PAGINATION_CURSOR_SECRET": secret}))
+	assert.NoError(t, err)
+	assert.Equal(t, config.Secret(secret), cfg.Pagination.CursorSecret)
+
+	var out bytes.Buffer
+	assert.NoError(t, cfg.Write(&out))
+	assert.Contains(t, out.String(), "cursor_secret: '[REDACTED]'")
+	assert.NotContains(t, out.String(), secret)
+}
+
+func TestConfig_WriteRoundTrip(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Addr = ":9000"
+	cfg.Database.ConnMaxIdleTime = config.Duration(90 * time.Second)
+
+	var out bytes.Buffer
+	assert.NoError(t, cfg.Write(&out))
+	assert.Contains(t, out.String(), "conn_max_idle_time: 1m30s")
+
+	file := writeFile(t, "printed.yaml", out.String())
+	loaded, opts, err := config.Load([]string{"-config", file, "-print-config"}, env(nil))
+	assert.NoError(t, err)
+	assert.True(t, opts.PrintConfig)
+	assert.Equal(t, cfg, loaded)
+}
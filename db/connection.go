@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// WithJournalMode sets the SQLite journal mode, e.g. "WAL", which lets
+// readers proceed while a write is in progress.
+func WithJournalMode(mode string) Option {
+	return func(o *options) {
+		o.pragmas = append(o.pragmas, fmt.Sprintf("journal_mode(%s)", mode))
+	}
+}
+
+// WithSynchronous sets how often SQLite syncs to disk: "OFF", "NORMAL",
+// "FULL" or "EXTRA". NORMAL is durable in WAL mode except on power loss.
+func WithSynchronous(level string) Option {
+	return func(o *options) {
+		o.pragmas = append(o.pragmas, fmt.Sprintf("synchronous(%s)", level))
+	}
+}
+
+// WithBusyTimeout sets how long a statement waits for a lock held by another
+// connection before failing with SQLITE_BUSY. The driver default is 5s.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.pragmas = append(o.pragmas, fmt.Sprintf("busy_timeout(%d)", d.Milliseconds()))
+	}
+}
+
+// WithForeignKeys turns enforcement of foreign key constraints on or off.
+func WithForeignKeys(on bool) Option {
+	return func(o *options) {
+		v := 0
+		if on {
+			v = 1
+		}
+		o.pragmas = append(o.pragmas, fmt.Sprintf("foreign_keys(%d)", v))
+	}
+}
+
+// WithSingleWriter splits the database into two connection pools. Writes
+// and transactions go to a pool of one connection that takes the write lock
+// as each transaction begins, so writers queue in the pool instead of
+// failing with SQLITE_BUSY. Queries outside transactions go to a pool of
+// read-only connections, which the other pool options size. It needs a
+// journal mode that lets readers and a writer overlap, i.e. WAL, and a
+// database file rather than ":memory:".
+func WithSingleWriter() Option {
+	return func(o *options) {
+		o.singleWriter = true
+	}
+}
+
+// dsn adds the given query parameters to path, which may already have some.
+func dsn(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
+
+// pragmaParams are the driver parameters that run each pragma on every new
+// connection.
+func pragmaParams(pragmas ...string) url.Values {
+	params := url.Values{}
+	for _, p := range pragmas {
+		params.Add("_pragma", p)
+	}
+	return params
+}
+
+// readPoolName is the name the read pool is registered under as a plugin.
+const readPoolName = "db:read_pool"
+
+// readPool is a plugin that sends queries made outside a transaction to a
+// pool of read-only connections.
+type readPool struct {
+	writer, reader *sql.DB
+}
+
+func (readPool) Name() string { return readPoolName }
+
+func (p *readPool) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("db:read_pool_query", p.route); err != nil {
+		return err
+	}
+	return cb.Row().Before("gorm:row").Register("db:read_pool_row", p.route)
+}
+
+// readKey is the statement setting that marks raw SQL as a read.
+const readKey = "db:read"
+
+// Read marks raw SQL run through the returned DB as a read, which a database
+// opened WithSingleWriter serves from its read pool like the queries GORM
+// builds itself. Raw SQL that is not marked runs on the writer, since it may
+// write whatever it starts with.
+func Read(db *gorm.DB) *gorm.DB {
+	return db.Set(readKey, true)
+}
+
+// route switches a statement to the read pool unless it runs in a
+// transaction or is raw SQL that was not marked with Read.
+func (p *readPool) route(tx *gorm.DB) {
+	if tx.Statement.ConnPool != p.writer {
+		return
+	}
+	if tx.Statement.SQL.Len() > 0 {
+		if read, _ := tx.Get(readKey); read != true {
+			return
+		}
+	}
+	tx.Statement.ConnPool = p.reader
+}
+
+// openReadPool opens the read-only pool for the database at path and
+// registers it on db, whose own pool becomes the single writer.
+func openReadPool(db *gorm.DB, path string, o *options) error {
+	writer, err := db.DB()
+	if err != nil {
+		return err
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+
+	// The journal mode is a property of the file, already set by the writer.
+	var pragmas []string
+	for _, p := range o.pragmas {
+		if !strings.HasPrefix(p, "journal_mode(") {
+			pragmas = append(pragmas, p)
+		}
+	}
+	reader, err := sql.Open(sqlite.DriverName, dsn(path, pragmaParams(append(pragmas, "query_only(1)")...)))
+	if err != nil {
+		return err
+	}
+	for _, configure := range o.pool {
+		configure(reader)
+	}
+	if err := reader.Ping(); err != nil {
+		reader.Close()
+		return err
+	}
+	return db.Use(&readPool{writer: writer, reader: reader})
+}
+
+// Pools returns the connection pools behind db by name: "write" and "read"
+// if it was opened WithSingleWriter, or just "default".
+func Pools(db *gorm.DB) (map[string]*sql.DB, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := db.Config.Plugins[readPoolName].(*readPool); ok {
+		return map[string]*sql.DB{"write": sqlDB, "read": p.reader}, nil
+	}
+	return map[string]*sql.DB{"default": sqlDB}, nil
+}
+
+// Close closes the connection pools behind db, waiting for queries in
+// progress to finish.
+func Close(db *gorm.DB) error {
+	pools, err := Pools(db)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range pools {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Ping checks that every connection pool can reach the database.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	pools, err := Pools(db)
+	if err != nil {
+		return err
+	}
+	for _, p := range pools {
+		if err := p.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"user-service/db"
+	"user-service/model"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestInitDB_Pragmas(t *testing.T) {
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "pragmas.db"),
+		db.WithLogger(logger.Discard),
+		db.WithJournalMode("WAL"),
+		db.WithSynchronous("NORMAL"),
+		db.WithBusyTimeout(1500*time.Millisecond),
+		db.WithForeignKeys(true),
+		db.WithSingleWriter(),
+	)
+	assert.NoError(t, err)
+
+	pools, err := db.Pools(gormDB)
+	assert.NoError(t, err)
+	assert.Len(t, pools, 2)
+	for name, pool := range pools {
+		for pragma, want := range map[string]string{
+			"journal_mode": "wal",
+			"synchronous":  "1",
+			"busy_timeout": "1500",
+			"foreign_keys": "1",
+		} {
+			var got string
+			assert.NoError(t, pool.QueryRow("PRAGMA "+pragma).Scan(&got))
+			assert.Equal(t, want, got, "%s pool: %s", name, pragma)
+		}
+	}
+
+	_, err = pools["read"].Exec("INSERT INTO users (name, created_at, updated_at) VALUES ('Sneaky', 1, 1)")
+	assert.Error(t, err, "the read pool is read-only")
+	assert.Equal(t, 1, pools["write"].Stats().MaxOpenConnections)
+}
+
+func TestInitDB_SingleWriterRoutesReads(t *testing.T) {
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "routes.db"),
+		db.WithLogger(logger.Discard), db.WithJournalMode("WAL"), db.WithSingleWriter(), db.WithMaxOpenConns(4))
+	assert.NoError(t, err)
+	pools, err := db.Pools(gormDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, pools["read"].Stats().MaxOpenConnections, "pool options size the read pool")
+
+	// Hold the only write connection, then read: the read must not wait for it.
+	tx := gormDB.Begin()
+	assert.NoError(t, tx.Create(&model.User{Name: "Pending", CreatedAt: 1, UpdatedAt: 1}).Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var count int64
+	assert.NoError(t, gormDB.WithContext(ctx).Model(&model.User{}).Count(&count).Error)
+	assert.Zero(t, count, "reads outside the transaction see committed data only")
+
+	var inTx int64
+	assert.NoError(t, tx.Model(&model.User{}).Count(&inTx).Error)
+	assert.Equal(t, int64(1), inTx, "reads in the transaction use its connection")
+	assert.NoError(t, tx.Commit().Error)
+
+	assert.NoError(t, gormDB.Model(&model.User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	assert.NoError(t, db.Close(gormDB))
+	for name, pool := range pools {
+		assert.Error(t, pool.Ping(), "%s pool is closed", name)
+	}
+}
+
+func TestInitDB_SingleWriterRoutesMarkedRawReads(t *testing.T) {
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "raw.db"),
+		db.WithLogger(logger.Discard), db.WithJournalMode("WAL"), db.WithSingleWriter())
+	assert.NoError(t, err)
+	defer db.Close(gormDB)
+
+	tx := gormDB.Begin()
+	defer tx.Rollback()
+	assert.NoError(t, tx.Create(&model.User{Name: "Pending", CreatedAt: 1, UpdatedAt: 1}).Error)
+
+	// A CTE is a read the SQL prefix does not give away; marked, it must not
+	// wait for the held write connection.
+	const cte = "WITH live AS (SELECT id FROM users WHERE deleted_at IS NULL) SELECT COUNT(*) FROM live"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var count int64
+	assert.NoError(t, db.Read(gormDB.WithContext(ctx)).Raw(cte).Scan(&count).Error)
+	assert.Zero(t, count)
+
+	// Unmarked raw SQL may write, so it queues for the writer.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, gormDB.WithContext(ctx).Raw(cte).Scan(&count).Error, context.DeadlineExceeded)
+}
+
+// TestInitDB_ConcurrentCreates is a load test: many goroutines create users
+// the way the repository does while others list them. With WAL and a single
+// writer none of them may fail with "database is locked".
+func TestInitDB_ConcurrentCreates(t *testing.T) {
+	const workers, perWorker = 32, 25
+
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "load.db"),
+		db.WithLogger(logger.Discard),
+		db.WithJournalMode("WAL"),
+		db.WithSynchronous("NORMAL"),
+		db.WithBusyTimeout(5*time.Second),
+		db.WithSingleWriter(),
+		db.WithMaxOpenConns(8),
+	)
+	assert.NoError(t, err)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := gormDB.Transaction(func(tx *gorm.DB) error {
+					now := time.Now().UnixMicro()
+					user := model.User{Name: fmt.Sprintf("Load %d %d", w, i), CreatedAt: now, UpdatedAt: now}
+					if err := tx.Create(&user).Error; err != nil {
+						return err
+					}
+					return db.IndexTrigrams(tx, user)
+				})
+				var page []model.User
+				readErr := gormDB.Where("deleted_at IS NULL").Order("created_at desc, id desc").Limit(10).Find(&page).Error
+
+				mu.Lock()
+				for _, e := range []error{err, readErr} {
+					if e != nil {
+						errs = append(errs, e)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, errs)
+	var count int64
+	assert.NoError(t, gormDB.Model(&model.User{}).Count(&count).Error)
+	assert.Equal(t, int64(workers*perWorker), count)
+}
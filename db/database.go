@@ -1,21 +1,153 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"user-service/fuzzy"
 	"user-service/model"
 )
 
-// InitDB initializes the SQLite database using a pure Go driver.
-func InitDB(path string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+// Option customizes the database opened by InitDB.
+type Option func(*options)
+
+type options struct {
+	config       gorm.Config
+	pool         []func(*sql.DB)
+	pragmas      []string
+	singleWriter bool
+	plugins      []gorm.Plugin
+	migrate      MigrateMode
+}
+
+// MigrateMode says what InitDB does about pending migrations.
+type MigrateMode int
+
+const (
+	// MigrateAuto applies pending migrations. It is the default.
+	MigrateAuto MigrateMode = iota
+	// MigrateCheck fails with ErrPendingMigrations if any are pending,
+	// leaving schema changes to an explicit migrate step.
+	MigrateCheck
+	// MigrateSkip leaves the schema alone, for tools that manage it.
+	MigrateSkip
+)
+
+// WithMigrateMode sets what InitDB does about pending migrations. Applied
+// migrations whose files have changed fail InitDB in every mode but
+// MigrateSkip.
+func WithMigrateMode(mode MigrateMode) Option {
+	return func(o *options) {
+		o.migrate = mode
+	}
+}
+
+// WithLogLevel sets which statements and errors GORM logs.
+func WithLogLevel(level logger.LogLevel) Option {
+	return func(o *options) {
+		o.config.Logger = logger.Default.LogMode(level)
+	}
+}
+
+// WithMaxOpenConns limits the number of open connections; 0 means unlimited.
+func WithMaxOpenConns(n int) Option {
+	return func(o *options) {
+		o.pool = append(o.pool, func(db *sql.DB) { db.SetMaxOpenConns(n) })
+	}
+}
+
+// WithMaxIdleConns limits the number of idle connections kept in the pool.
+func WithMaxIdleConns(n int) Option {
+	return func(o *options) {
+		o.pool = append(o.pool, func(db *sql.DB) { db.SetMaxIdleConns(n) })
+	}
+}
+
+// WithConnMaxLifetime closes connections older than d; 0 keeps them forever.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(o *options) {
+		o.pool = append(o.pool, func(db *sql.DB) { db.SetConnMaxLifetime(d) })
+	}
+}
+
+// WithConnMaxIdleTime closes connections idle for longer than d; 0 keeps them forever.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(o *options) {
+		o.pool = append(o.pool, func(db *sql.DB) { db.SetConnMaxIdleTime(d) })
+	}
+}
+
+// InitDB initializes the SQLite database using a pure Go driver. path may
+// carry driver parameters such as "?_pragma=busy_timeout(0)".
+func InitDB(path string, opts ...Option) (*gorm.DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	params := pragmaParams(o.pragmas...)
+	if o.singleWriter {
+		// Take the write lock at BEGIN, not at the first write, so a
+		// transaction never has to upgrade a read lock, which SQLite may
+		// refuse without waiting.
+		params.Set("_txlock", "immediate")
+	}
+	db, err := gorm.Open(sqlite.Open(dsn(path, params)), &o.config)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&model.User{}); err != nil {
+	if o.singleWriter {
+		if err := openReadPool(db, path, &o); err != nil {
+			return nil, err
+		}
+	} else {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		for _, configure := range o.pool {
+			configure(sqlDB)
+		}
+	}
+	for _, plugin := range append([]gorm.Plugin{contextErrors{}}, o.plugins...) {
+		if err := db.Use(plugin); err != nil {
+			return nil, err
+		}
+	}
+
+	switch o.migrate {
+	case MigrateAuto:
+		err = Migrate(db)
+	case MigrateCheck:
+		err = CheckSchema(context.Background(), db)
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// trigramBatchSize keeps multi-row trigram inserts under SQLite's bound
+// parameter limit.
+const trigramBatchSize = 400
+
+// IndexTrigrams records the name trigrams of the given users. It must run in
+// the same transaction as the write that created or renamed them.
+func IndexTrigrams(tx *gorm.DB, users ...model.User) error {
+	var rows []map[string]interface{}
+	for _, user := range users {
+		for _, g := range fuzzy.Trigrams(user.Name) {
+			rows = append(rows, map[string]interface{}{"trigram": g, "user_id": user.ID})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Table("user_name_trigrams").CreateInBatches(rows, trigramBatchSize).Error
+}
@@ -2,13 +2,25 @@
 package db_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"user-service/db"
+	"user-service/logging"
 	"user-service/model"
+	"user-service/tracing"
 
+	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func TestInitDB(t *testing.T) {
@@ -46,3 +58,153 @@ func TestInitDB(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrate_BackfillsSearchIndexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Simulate a database created before the search index existed.
+	legacy, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.AutoMigrate(&model.User{}))
+	assert.NoError(t, legacy.Create(&model.User{Name: "Legacy User", CreatedAt: 1, UpdatedAt: 1}).Error)
+
+	assert.NoError(t, db.Migrate(legacy))
+	assert.NoError(t, db.Migrate(legacy), "migrating twice must be a no-op")
+
+	var hits int64
+	err = legacy.Raw("SELECT COUNT(*) FROM users_fts WHERE users_fts MATCH ?", "legacy").Scan(&hits).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), hits)
+
+	var grams int64
+	err = legacy.Table("user_name_trigrams").Where("trigram = ?", " le").Count(&grams).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), grams)
+}
+
+func TestInitDB_PoolOptions(t *testing.T) {
+	dbInstance, err := db.InitDB(filepath.Join(t.TempDir(), "pool.db"),
+		db.WithMaxOpenConns(3),
+		db.WithMaxIdleConns(1),
+	)
+	assert.NoError(t, err)
+
+	sqlDB, err := dbInstance.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestHealthChecks(t *testing.T) {
+	ctx := context.Background()
+
+	empty, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "empty.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Ping(ctx, empty))
+	assert.ErrorIs(t, db.CheckSchema(ctx, empty), db.ErrPendingMigrations)
+
+	migrated, err := db.InitDB(filepath.Join(t.TempDir(), "migrated.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, db.CheckSchema(ctx, migrated))
+
+	assert.NoError(t, db.Close(migrated))
+	assert.Error(t, db.Ping(ctx, migrated))
+}
+
+func TestNewLogger(t *testing.T) {
+	var out bytes.Buffer
+	log := logging.New(&out, slog.LevelDebug, nil)
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "log.db"), db.WithLogger(db.NewLogger(log, logger.Warn)))
+	assert.NoError(t, err)
+
+	ctx := logging.WithRequestID(context.Background(), "req-9")
+	err = gormDB.WithContext(ctx).Exec("SELECT * FROM no_such_table").Error
+	assert.Error(t, err)
+
+	var missing model.User
+	err = gormDB.WithContext(ctx).First(&missing, 42).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Only the failed statement is logged: successful ones are below the
+	// configured level and missing records are not failures.
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 1) {
+		var rec map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+		assert.Equal(t, "ERROR", rec["level"])
+		assert.Equal(t, "query failed", rec["msg"])
+		assert.Equal(t, "SELECT * FROM no_such_table", rec["sql"])
+		assert.Equal(t, "req-9", rec["request_id"])
+	}
+}
+
+// spanRecorder keeps exported spans in memory.
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(d tracing.SpanData) error {
+	r.spans = append(r.spans, d)
+	return nil
+}
+
+func TestWithTracer(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer := tracing.NewTracer(rec)
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "trace.db"), db.WithLogger(logger.Discard), db.WithTracer(tracer))
+	assert.NoError(t, err)
+	assert.Empty(t, rec.spans, "migrations run outside any trace")
+
+	// Statements outside a trace are not recorded.
+	assert.NoError(t, gormDB.Create(&model.User{Name: "Untraced"}).Error)
+	assert.Empty(t, rec.spans)
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	assert.NoError(t, gormDB.WithContext(ctx).Create(&model.User{Name: "Alice"}).Error)
+	var users []model.User
+	assert.NoError(t, gormDB.WithContext(ctx).Find(&users).Error)
+	var missing model.User
+	assert.ErrorIs(t, gormDB.WithContext(ctx).First(&missing, 42).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, gormDB.WithContext(ctx).Exec("SELECT * FROM no_such_table").Error)
+
+	attrs := func(d tracing.SpanData) map[string]any {
+		m := make(map[string]any)
+		for _, a := range d.Attrs {
+			m[a.Key] = a.Value
+		}
+		return m
+	}
+	if assert.Len(t, rec.spans, 4) {
+		for _, s := range rec.spans {
+			assert.Equal(t, parent.SpanContext().TraceID, s.TraceID)
+			assert.Equal(t, parent.SpanContext().SpanID, s.Parent)
+			assert.Equal(t, tracing.KindClient, s.Kind)
+		}
+
+		create, find, first, raw := rec.spans[0], rec.spans[1], rec.spans[2], rec.spans[3]
+		assert.Equal(t, "gorm.create", create.Name)
+		assert.Contains(t, attrs(create)["db.statement"], "INSERT INTO `users`")
+		assert.Equal(t, "users", attrs(create)["db.sql.table"])
+		assert.Equal(t, int64(1), attrs(create)["db.rows_affected"])
+		assert.NotContains(t, attrs(create)["db.statement"], "Alice", "bound values are not recorded")
+
+		assert.Equal(t, "gorm.query", find.Name)
+		assert.Equal(t, int64(2), attrs(find)["db.rows_affected"])
+
+		assert.Empty(t, first.Err, "a missing record is not a failure")
+		assert.Equal(t, "gorm.raw", raw.Name)
+		assert.Contains(t, raw.Err, "no_such_table")
+	}
+}
+
+func TestInitDB_InterruptedStatementReportsDeadline(t *testing.T) {
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "deadline.db"), db.WithLogger(logger.Discard))
+	assert.NoError(t, err)
+
+	// The driver interrupts a statement whose context ends while it runs and
+	// reports only the interruption.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = gormDB.WithContext(ctx).Exec(
+		"CREATE TABLE endless AS WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT x FROM c").Error
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// contextErrors is a plugin that adds the context's error to the error of a
+// statement that failed because its context ended. The driver interrupts such
+// a statement and reports only that it was interrupted, which would
+// otherwise hide that the caller ran out of time.
+type contextErrors struct{}
+
+func (contextErrors) Name() string { return "db:context_errors" }
+
+func (contextErrors) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	type register func(name string, fn func(*gorm.DB)) error
+	for _, r := range []register{
+		cb.Create().After("gorm:create").Register,
+		cb.Query().After("gorm:query").Register,
+		cb.Update().After("gorm:update").Register,
+		cb.Delete().After("gorm:delete").Register,
+		cb.Row().After("gorm:row").Register,
+		cb.Raw().After("gorm:raw").Register,
+	} {
+		if err := r("db:context_errors", addContextError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addContextError(tx *gorm.DB) {
+	if tx.Error == nil || tx.Statement.Context == nil {
+		return
+	}
+	if err := tx.Statement.Context.Err(); err != nil && !errors.Is(tx.Error, err) {
+		tx.Error = fmt.Errorf("%w: %w", err, tx.Error)
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"user-service/logging"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is how long a statement may run before it is logged as slow.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// slogLogger sends GORM's log output to a slog.Logger, passing on the
+// statement's context so records carry its request ID.
+type slogLogger struct {
+	log   *slog.Logger
+	level logger.LogLevel
+}
+
+// NewLogger returns a GORM logger writing to log. Failed statements are
+// logged at error, slow ones at warn and, with logger.Info, every statement
+// at debug. Missing records are not treated as failures.
+func NewLogger(log *slog.Logger, level logger.LogLevel) logger.Interface {
+	return &slogLogger{log: log, level: level}
+}
+
+// WithLogger sets the logger GORM reports statements and errors to.
+func WithLogger(l logger.Interface) Option {
+	return func(o *options) {
+		o.config.Logger = l
+	}
+}
+
+func (l *slogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &slogLogger{log: l.log, level: level}
+}
+
+func (l *slogLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.log.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.log.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.log.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "duration_ms", logging.Millis(elapsed), "error", err)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.log.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "duration_ms", logging.Millis(elapsed))
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.log.DebugContext(ctx, "query", "sql", sql, "rows", rows, "duration_ms", logging.Millis(elapsed))
+	}
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"user-service/model"
+)
+
+var (
+	// ErrPendingMigrations means the schema is behind this binary.
+	ErrPendingMigrations = errors.New("database has pending migrations")
+
+	// ErrChecksumMismatch means a migration was edited after it was applied.
+	ErrChecksumMismatch = errors.New("applied migration does not match its file")
+
+	// ErrNothingToRollBack means no migration known to this binary is applied.
+	ErrNothingToRollBack = errors.New("no migration to roll back")
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationHooks run after the SQL of the migration with the same version,
+// in the same transaction, for data changes that need Go code.
+var migrationHooks = map[int]func(tx *gorm.DB) error{
+	3: backfillTrigrams,
+}
+
+// migration is one step of the schema, read from a pair of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+type migration struct {
+	version  int
+	name     string
+	up, down string
+	checksum string
+}
+
+// migrations are the embedded migrations in version order.
+var migrations = mustLoadMigrations(migrationFiles, "migrations")
+
+func mustLoadMigrations(fsys fs.FS, dir string) []migration {
+	ms, err := loadMigrations(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, e := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), ".")
+		prefix, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || !found || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: want <version>_<name>.up.sql or .down.sql", e.Name())
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(data)
+			sum := sha256.Sum256(data)
+			m.checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	ms := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.version, m.name)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].version < ms[j].version })
+	return ms, nil
+}
+
+// schemaMigration is a row of schema_migrations, recording an applied
+// migration and the checksum of its up file at the time.
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	Checksum  string `gorm:"not null"`
+	AppliedAt int64  `gorm:"not null"` // Unix microseconds
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+const schemaMigrationsDDL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at INTEGER NOT NULL
+)`
+
+// MigrationState is whether a migration has been applied.
+type MigrationState string
+
+const (
+	// MigrationPending has not been applied yet.
+	MigrationPending MigrationState = "pending"
+	// MigrationApplied has been applied and its file is unchanged since.
+	MigrationApplied MigrationState = "applied"
+	// MigrationModified has been applied, but its up file has changed since.
+	MigrationModified MigrationState = "modified"
+	// MigrationUnknown has been applied by a newer binary that has migrations
+	// this one lacks.
+	MigrationUnknown MigrationState = "unknown"
+)
+
+// MigrationStatus describes one migration known to the binary or recorded in
+// the database.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     MigrationState
+	AppliedAt time.Time // zero while pending
+}
+
+// MigrationStatuses lists every migration in version order with its state in
+// db. It does not change the database.
+func MigrationStatuses(ctx context.Context, db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.version, Name: m.name, State: MigrationPending}
+		if a, ok := applied[m.version]; ok {
+			s.State = MigrationApplied
+			if a.Checksum != m.checksum {
+				s.State = MigrationModified
+			}
+			s.AppliedAt = time.UnixMicro(a.AppliedAt)
+			delete(applied, m.version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, a := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version: a.Version, Name: a.Name, State: MigrationUnknown, AppliedAt: time.UnixMicro(a.AppliedAt),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// appliedMigrations reads schema_migrations by version. A database that has
+// never been migrated has none.
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	applied := make(map[int]schemaMigration)
+	if !db.Migrator().HasTable(schemaMigration{}) {
+		return applied, nil
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// CheckSchema reports an error if db is missing migrations this binary
+// needs, wrapping ErrPendingMigrations, or if an applied migration has since
+// been edited, wrapping ErrChecksumMismatch. Migrations applied by a newer
+// binary are accepted, so an older one can keep serving during a rollout.
+func CheckSchema(ctx context.Context, db *gorm.DB) error {
+	statuses, err := MigrationStatuses(ctx, db)
+	if err != nil {
+		return err
+	}
+	return checkStatuses(statuses)
+}
+
+func checkStatuses(statuses []MigrationStatus) error {
+	var pending []string
+	for _, s := range statuses {
+		switch s.State {
+		case MigrationModified:
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, s.Version, s.Name)
+		case MigrationPending:
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Migrate applies every pending migration in version order, each in its own
+// transaction together with its schema_migrations row. It refuses to run if
+// an applied migration has been edited since.
+//
+// The first migrations recreate the schema GORM's AutoMigrate used to build,
+// and are idempotent, so databases created before versioned migrations are
+// adopted once their users table has caught up with that schema.
+func Migrate(db *gorm.DB) error {
+	if !db.Migrator().HasTable(schemaMigration{}) && db.Migrator().HasTable("users") {
+		if err := adoptLegacyUsers(db); err != nil {
+			return fmt.Errorf("adopt existing schema: %w", err)
+		}
+	}
+	if err := db.Exec(schemaMigrationsDDL).Error; err != nil {
+		return err
+	}
+	statuses, err := MigrationStatuses(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	if err := checkStatuses(statuses); err != nil && !errors.Is(err, ErrPendingMigrations) {
+		return err
+	}
+
+	for _, m := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Another process may have applied it since the statuses were read.
+			var n int64
+			if err := tx.Model(schemaMigration{}).Where("version = ?", m.version).Count(&n).Error; err != nil || n > 0 {
+				return err
+			}
+
+			if err := tx.Exec(m.up).Error; err != nil {
+				return err
+			}
+			if hook := migrationHooks[m.version]; hook != nil {
+				if err := hook(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&schemaMigration{
+				Version:   m.version,
+				Name:      m.name,
+				Checksum:  m.checksum,
+				AppliedAt: time.Now().UnixMicro(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown rolls back the most recently applied migration and returns
+// it, or returns ErrNothingToRollBack. Migrations applied by a newer binary
+// cannot be rolled back by this one.
+func MigrateDown(db *gorm.DB) (MigrationStatus, error) {
+	statuses, err := MigrationStatuses(context.Background(), db)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	var last *MigrationStatus
+	for i := range statuses {
+		if statuses[i].State != MigrationPending {
+			last = &statuses[i]
+		}
+	}
+	if last == nil {
+		return MigrationStatus{}, ErrNothingToRollBack
+	}
+	if last.State == MigrationUnknown {
+		return MigrationStatus{}, fmt.Errorf("migration %04d_%s was applied by a newer binary", last.Version, last.Name)
+	}
+
+	var down string
+	for _, m := range migrations {
+		if m.version == last.Version {
+			down = m.down
+		}
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{}, last.Version).Error
+	})
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("migration %04d_%s: %w", last.Version, last.Name, err)
+	}
+	last.State = MigrationPending
+	last.AppliedAt = time.Time{}
+	return *last, nil
+}
+
+// legacyUserColumns were added to users by AutoMigrate after its first
+// release, so databases from that era may lack them.
+var legacyUserColumns = []struct{ name, ddl string }{
+	{"version", "ALTER TABLE `users` ADD COLUMN `version` integer NOT NULL DEFAULT 1"},
+	{"deleted_at", "ALTER TABLE `users` ADD COLUMN `deleted_at` integer"},
+}
+
+// adoptLegacyUsers adds the columns an AutoMigrate-created users table may be
+// missing, so that the baseline migration applies to it.
+func adoptLegacyUsers(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, col := range legacyUserColumns {
+			if tx.Migrator().HasColumn("users", col.name) {
+				continue
+			}
+			if err := tx.Exec(col.ddl).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillTrigrams indexes the names of users created before the trigram
+// table existed, replacing whatever rows an earlier schema left behind.
+func backfillTrigrams(tx *gorm.DB) error {
+	if err := tx.Exec("DELETE FROM user_name_trigrams").Error; err != nil {
+		return err
+	}
+	var users []model.User
+	return tx.Select("id", "name").FindInBatches(&users, 500, func(batch *gorm.DB, _ int) error {
+		return IndexTrigrams(batch, users...)
+	}).Error
+}
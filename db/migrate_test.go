@@ -0,0 +1,140 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"user-service/db"
+	"user-service/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openEmpty(t *testing.T) *gorm.DB {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")),
+		&gorm.Config{Logger: logger.Discard})
+	assert.NoError(t, err)
+	return gormDB
+}
+
+func states(t *testing.T, gormDB *gorm.DB) []db.MigrationState {
+	statuses, err := db.MigrationStatuses(context.Background(), gormDB)
+	assert.NoError(t, err)
+	var got []db.MigrationState
+	for _, s := range statuses {
+		got = append(got, s.State)
+	}
+	return got
+}
+
+func TestMigrate_UpAndDown(t *testing.T) {
+	gormDB := openEmpty(t)
+	ctx := context.Background()
+
+	statuses, err := db.MigrationStatuses(ctx, gormDB)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, statuses) {
+		assert.Equal(t, 1, statuses[0].Version)
+		assert.Equal(t, "create_users", statuses[0].Name)
+	}
+	for _, s := range statuses {
+		assert.Equal(t, db.MigrationPending, s.State)
+		assert.True(t, s.AppliedAt.IsZero())
+	}
+	assert.False(t, gormDB.Migrator().HasTable("schema_migrations"), "reading the status changes nothing")
+
+	assert.NoError(t, db.Migrate(gormDB))
+	assert.NoError(t, db.Migrate(gormDB), "migrating twice must be a no-op")
+	assert.NoError(t, db.CheckSchema(ctx, gormDB))
+	for _, s := range states(t, gormDB) {
+		assert.Equal(t, db.MigrationApplied, s)
+	}
+
+	// Roll everything back, newest first.
+	for i := len(statuses) - 1; i >= 0; i-- {
+		reverted, err := db.MigrateDown(gormDB)
+		assert.NoError(t, err)
+		assert.Equal(t, statuses[i].Version, reverted.Version)
+		assert.Equal(t, db.MigrationPending, reverted.State)
+		assert.ErrorIs(t, db.CheckSchema(ctx, gormDB), db.ErrPendingMigrations)
+	}
+	_, err = db.MigrateDown(gormDB)
+	assert.ErrorIs(t, err, db.ErrNothingToRollBack)
+	for _, table := range []string{"users", "users_fts", "user_name_trigrams"} {
+		assert.False(t, gormDB.Migrator().HasTable(table), table)
+	}
+
+	assert.NoError(t, db.Migrate(gormDB), "down migrations leave a schema that migrates up again")
+	assert.NoError(t, gormDB.Create(&model.User{Name: "Back Again"}).Error)
+}
+
+func TestMigrate_ChecksumMismatch(t *testing.T) {
+	gormDB := openEmpty(t)
+	ctx := context.Background()
+	assert.NoError(t, db.Migrate(gormDB))
+	assert.NoError(t, gormDB.Exec("UPDATE schema_migrations SET checksum = 'edited' WHERE version = 2").Error)
+
+	assert.Equal(t, db.MigrationModified, states(t, gormDB)[1])
+	assert.ErrorIs(t, db.CheckSchema(ctx, gormDB), db.ErrChecksumMismatch)
+	assert.ErrorIs(t, db.Migrate(gormDB), db.ErrChecksumMismatch)
+}
+
+func TestMigrate_NewerDatabase(t *testing.T) {
+	gormDB := openEmpty(t)
+	assert.NoError(t, db.Migrate(gormDB))
+	err := gormDB.Exec("INSERT INTO schema_migrations VALUES (9999, 'from_the_future', 'x', 1)").Error
+	assert.NoError(t, err)
+
+	got := states(t, gormDB)
+	assert.Equal(t, db.MigrationUnknown, got[len(got)-1])
+	assert.NoError(t, db.CheckSchema(context.Background(), gormDB), "an older binary may serve a newer schema")
+	assert.NoError(t, db.Migrate(gormDB))
+
+	_, err = db.MigrateDown(gormDB)
+	assert.ErrorContains(t, err, "9999_from_the_future was applied by a newer binary")
+}
+
+func TestMigrate_AdoptsAutoMigrateSchema(t *testing.T) {
+	gormDB := openEmpty(t)
+
+	// The users table as the first AutoMigrate release created it, before
+	// rows were versioned or soft-deleted.
+	err := gormDB.Exec("CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`created_at` integer,`updated_at` integer)").Error
+	assert.NoError(t, err)
+	assert.NoError(t, gormDB.Exec("INSERT INTO users (name, created_at, updated_at) VALUES ('Old Timer', 1, 1)").Error)
+
+	assert.NoError(t, db.Migrate(gormDB))
+	assert.NoError(t, db.CheckSchema(context.Background(), gormDB))
+
+	var user model.User
+	assert.NoError(t, gormDB.First(&user).Error)
+	assert.Equal(t, uint64(1), user.Version)
+	assert.Nil(t, user.DeletedAt)
+
+	var grams int64
+	err = gormDB.Table("user_name_trigrams").Where("user_id = ?", user.ID).Count(&grams).Error
+	assert.NoError(t, err)
+	assert.NotZero(t, grams)
+}
+
+func TestInitDB_MigrateModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modes.db")
+
+	_, err := db.InitDB(path, db.WithLogger(logger.Discard), db.WithMigrateMode(db.MigrateCheck))
+	assert.ErrorIs(t, err, db.ErrPendingMigrations)
+
+	skipped, err := db.InitDB(path, db.WithLogger(logger.Discard), db.WithMigrateMode(db.MigrateSkip))
+	assert.NoError(t, err)
+	assert.False(t, skipped.Migrator().HasTable("users"))
+	assert.NoError(t, db.Close(skipped))
+
+	_, err = db.InitDB(path, db.WithLogger(logger.Discard))
+	assert.NoError(t, err)
+
+	_, err = db.InitDB(path, db.WithLogger(logger.Discard), db.WithMigrateMode(db.MigrateCheck))
+	assert.NoError(t, err)
+}
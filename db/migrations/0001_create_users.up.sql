@@ -0,0 +1,11 @@
+-- The users table as first created by GORM's AutoMigrate. IF NOT EXISTS lets
+-- databases created before versioned migrations adopt this baseline.
+CREATE TABLE IF NOT EXISTS `users` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`name` text,
+	`created_at` integer,
+	`updated_at` integer,
+	`version` integer NOT NULL DEFAULT 1,
+	`deleted_at` integer
+);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
@@ -0,0 +1,4 @@
+DROP TRIGGER users_fts_au;
+DROP TRIGGER users_fts_ad;
+DROP TRIGGER users_fts_ai;
+DROP TABLE users_fts;
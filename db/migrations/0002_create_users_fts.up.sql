@@ -0,0 +1,19 @@
+-- An external-content FTS5 index over users.name, kept in sync by triggers.
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+	name,
+	content='users',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS users_fts_ai AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER IF NOT EXISTS users_fts_ad AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER IF NOT EXISTS users_fts_au AFTER UPDATE OF name ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+-- Index users that existed before the table did.
+INSERT INTO users_fts(users_fts) VALUES ('rebuild');
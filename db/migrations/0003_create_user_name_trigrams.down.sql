@@ -0,0 +1,3 @@
+DROP TRIGGER user_name_trigrams_au;
+DROP TRIGGER user_name_trigrams_ad;
+DROP TABLE user_name_trigrams;
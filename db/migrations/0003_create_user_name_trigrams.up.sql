@@ -0,0 +1,15 @@
+-- The table backing fuzzy name search. Rows are written by the repository,
+-- and backfilled by this migration, since folding names needs Go code. Deletes
+-- and renames are cleaned up by triggers.
+CREATE TABLE IF NOT EXISTS user_name_trigrams (
+	trigram TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	PRIMARY KEY (trigram, user_id)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_user_name_trigrams_user_id ON user_name_trigrams(user_id);
+CREATE TRIGGER IF NOT EXISTS user_name_trigrams_ad AFTER DELETE ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS user_name_trigrams_au AFTER UPDATE OF name ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
@@ -0,0 +1,36 @@
+-- Rebuild users as it was before 0004, without its constraints and indexes.
+CREATE TABLE users_old (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`name` text,
+	`created_at` integer,
+	`updated_at` integer,
+	`version` integer NOT NULL DEFAULT 1,
+	`deleted_at` integer
+);
+INSERT INTO users_old (id, name, created_at, updated_at, version, deleted_at)
+	SELECT id, name, created_at, updated_at, version, deleted_at FROM users;
+
+DELETE FROM sqlite_sequence WHERE name = 'users_old';
+UPDATE sqlite_sequence SET name = 'users_old' WHERE name = 'users';
+
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
+
+CREATE TRIGGER users_fts_ai AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER users_fts_ad AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER users_fts_au AFTER UPDATE OF name ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER user_name_trigrams_ad AFTER DELETE ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
+CREATE TRIGGER user_name_trigrams_au AFTER UPDATE OF name ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
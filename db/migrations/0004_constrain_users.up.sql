@@ -0,0 +1,46 @@
+-- SQLite cannot add constraints to an existing table, so users is rebuilt
+-- with them and its rows copied over. Rows that break a constraint make this
+-- migration fail; fix them and run it again.
+CREATE TABLE users_new (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`name` text NOT NULL CHECK (length(trim(`name`)) > 0 AND length(`name`) <= 255),
+	`created_at` integer NOT NULL CHECK (`created_at` > 0),
+	`updated_at` integer NOT NULL CHECK (`updated_at` >= `created_at`),
+	`version` integer NOT NULL DEFAULT 1 CHECK (`version` > 0),
+	`deleted_at` integer CHECK (`deleted_at` > 0)
+);
+INSERT INTO users_new (id, name, created_at, updated_at, version, deleted_at)
+	SELECT id, name, created_at, updated_at, version, deleted_at FROM users;
+
+-- Keep the AUTOINCREMENT high-water mark, so IDs of deleted users are not
+-- handed out again.
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+UPDATE sqlite_sequence SET name = 'users_new' WHERE name = 'users';
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+-- Active users newest first: offset and cursor pages and their counts.
+CREATE INDEX idx_users_active_created_at ON users(created_at, id) WHERE deleted_at IS NULL;
+-- Every user newest first, for listings that include deleted users.
+CREATE INDEX idx_users_created_at ON users(created_at, id);
+-- Deleted users by deletion time, for restore windows and purges.
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Dropping the old table dropped its triggers.
+CREATE TRIGGER users_fts_ai AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER users_fts_ad AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER users_fts_au AFTER UPDATE OF name ON users BEGIN
+	INSERT INTO users_fts(users_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO users_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER user_name_trigrams_ad AFTER DELETE ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
+CREATE TRIGGER user_name_trigrams_au AFTER UPDATE OF name ON users BEGIN
+	DELETE FROM user_name_trigrams WHERE user_id = old.id;
+END;
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"errors"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+// WithTracer records a client span for every statement run on behalf of a
+// traced operation, i.e. whose context already carries a span. Statements
+// outside any trace, such as migrations, are not recorded.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(o *options) {
+		o.plugins = append(o.plugins, tracingPlugin{tracer})
+	}
+}
+
+// tracingPlugin wraps GORM's statement callbacks in spans.
+type tracingPlugin struct {
+	tracer *tracing.Tracer
+}
+
+// spanInstanceKey stores a statement's span between its callbacks.
+const spanInstanceKey = "tracing:span"
+
+func (tracingPlugin) Name() string { return "tracing" }
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	type register func(name string, fn func(*gorm.DB)) error
+	ops := []struct {
+		name          string
+		before, after register
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, op := range ops {
+		if err := op.before("tracing:before_"+op.name, p.before(op.name)); err != nil {
+			return err
+		}
+		if err := op.after("tracing:after_"+op.name, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p tracingPlugin) before(op string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if tracing.SpanFromContext(ctx) == nil {
+			return
+		}
+		_, span := p.tracer.Start(ctx, "gorm."+op,
+			tracing.WithKind(tracing.KindClient),
+			tracing.WithAttributes(tracing.String("db.system", "sqlite")))
+		tx.InstanceSet(spanInstanceKey, span)
+	}
+}
+
+// after ends the statement's span with the SQL, without bound values, and
+// the number of rows it affected or returned.
+func (tracingPlugin) after(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(spanInstanceKey)
+	if !ok {
+		return
+	}
+	span := v.(*tracing.Span)
+	span.SetAttributes(
+		tracing.String("db.statement", tx.Statement.SQL.String()),
+		tracing.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Statement.Table != "" {
+		span.SetAttributes(tracing.String("db.sql.table", tx.Statement.Table))
+	}
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+	}
+	span.End()
+}
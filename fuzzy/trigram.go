@@ -0,0 +1,83 @@
+// Package fuzzy implements typo-tolerant name matching using trigram
+// similarity over case- and diacritic-folded text.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// letterFolds covers letters that carry no combining mark in NFD and so
+// survive mark stripping.
+var letterFolds = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "ð", "d", "þ", "th", "ı", "i",
+)
+
+// Fold lowercases s, strips diacritics and collapses everything that is not a
+// letter or digit into single spaces, so "  Jöhn-Doe " becomes "john doe".
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, strings.ToLower(s))
+	if err != nil {
+		stripped = strings.ToLower(s)
+	}
+	stripped = letterFolds.Replace(stripped)
+
+	words := strings.FieldsFunc(stripped, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Trigrams returns the sorted, de-duplicated trigrams of the folded form of s.
+// Each word is padded with two leading spaces and one trailing space, so
+// word starts weigh more than word ends.
+func Trigrams(s string) []string {
+	set := make(map[string]struct{})
+	for _, word := range strings.Fields(Fold(s)) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+
+	grams := make([]string, 0, len(set))
+	for g := range set {
+		grams = append(grams, g)
+	}
+	sort.Strings(grams)
+	return grams
+}
+
+// Similarity returns the Jaccard similarity of the trigram sets of a and b,
+// from 0 (nothing in common) to 1 (identical after folding).
+func Similarity(a, b string) float64 {
+	return SetSimilarity(Trigrams(a), Trigrams(b))
+}
+
+// SetSimilarity is Similarity over trigram sets already produced by Trigrams.
+func SetSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,58 @@
+package fuzzy_test
+
+import (
+	"testing"
+	"user-service/fuzzy"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"John Doe", "john doe"},
+		{"  Jöhn-Doe ", "john doe"},
+		{"Zoë Saldaña", "zoe saldana"},
+		{"Łukasz Søren Straße", "lukasz soren strasse"},
+		{"***", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, fuzzy.Fold(tt.input))
+		})
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, []string{"  j", " jo", "jo "}, fuzzy.Trigrams("Jo"))
+	assert.Equal(t, []string{"  a", " a "}, fuzzy.Trigrams("a a"))
+	assert.Empty(t, fuzzy.Trigrams(""))
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string
+		minSim float64
+		maxSim float64
+	}{
+		{"identical", "John Doe", "John Doe", 1, 1},
+		{"diacritics", "Jöhn Doe", "John Doe", 1, 1},
+		{"missing letter", "Jon Doe", "John Doe", 0.5, 0.6},
+		{"truncated word", "John Do", "John Doe", 0.65, 0.75},
+		{"unrelated", "Alice", "John Doe", 0, 0},
+		{"empty", "", "John Doe", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := fuzzy.Similarity(tt.a, tt.b)
+			assert.GreaterOrEqual(t, sim, tt.minSim)
+			assert.LessOrEqual(t, sim, tt.maxSim)
+			assert.Equal(t, sim, fuzzy.Similarity(tt.b, tt.a))
+		})
+	}
+}
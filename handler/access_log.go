@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"user-service/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userIDKey is the gin context key holding the ID of the user a request touched.
+const userIDKey = "user_id"
+
+// setUserID records the user a request touched, for the access log.
+func setUserID(c *gin.Context, id uint64) {
+	c.Set(userIDKey, id)
+}
+
+// AccessLog is middleware that logs one record per request with its method,
+// route template, status, latency, response size and, when known, the user
+// it touched. Query parameters are logged in a query group, so configured
+// redaction applies to them by name. Server errors are logged at error level
+// together with the underlying cause.
+func AccessLog(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", logging.Millis(time.Since(start))),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			params := make([]any, 0, len(query))
+			for _, name := range slices.Sorted(maps.Keys(query)) {
+				params = append(params, slog.String(name, strings.Join(query[name], ",")))
+			}
+			attrs = append(attrs, slog.Group("query", params...))
+		}
+		if id, ok := c.Get(userIDKey); ok {
+			attrs = append(attrs, slog.Any("user_id", id))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", strings.Join(c.Errors.Errors(), "; ")))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		log.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
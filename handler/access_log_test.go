@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/logging"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	h := NewUserHandler(mockSvc)
+
+	var out bytes.Buffer
+	r := gin.New()
+	r.Use(RequestID(), AccessLog(logging.New(&out, slog.LevelInfo, []string{"token"})))
+	r.GET("/users/:id", h.GetUser)
+
+	tests := []struct {
+		name     string
+		path     string
+		mockFunc func()
+		want     map[string]any
+	}{
+		{
+			name: "found",
+			path: "/users/7?include_deleted=true&token=secret",
+			mockFunc: func() {
+				mockSvc.EXPECT().GetUser(gomock.Any(), uint64(7)).Return(model.User{ID: 7, Name: "Alice"}, nil)
+			},
+			want: map[string]any{
+				"level":      "INFO",
+				"msg":        "request",
+				"method":     "GET",
+				"route":      "/users/:id",
+				"path":       "/users/7",
+				"status":     200.0,
+				"user_id":    7.0,
+				"request_id": "req-1",
+				"query":      map[string]any{"include_deleted": "true", "token": logging.Redacted},
+			},
+		},
+		{
+			name: "server error",
+			path: "/users/8",
+			mockFunc: func() {
+				mockSvc.EXPECT().GetUser(gomock.Any(), uint64(8)).Return(model.User{}, errors.New("disk on fire"))
+			},
+			want: map[string]any{
+				"level":      "ERROR",
+				"msg":        "request",
+				"method":     "GET",
+				"route":      "/users/:id",
+				"path":       "/users/8",
+				"status":     500.0,
+				"user_id":    8.0,
+				"request_id": "req-1",
+				"error":      "disk on fire",
+			},
+		},
+		{
+			name:     "unmatched route",
+			path:     "/nope",
+			mockFunc: func() {},
+			want: map[string]any{
+				"level":      "INFO",
+				"msg":        "request",
+				"method":     "GET",
+				"route":      unmatchedRoute,
+				"path":       "/nope",
+				"status":     404.0,
+				"request_id": "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			tt.mockFunc()
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			var rec map[string]any
+			assert.NoError(t, json.Unmarshal(out.Bytes(), &rec))
+			for _, key := range []string{"time", "latency_ms", "bytes", "client_ip"} {
+				assert.Contains(t, rec, key)
+				delete(rec, key)
+			}
+			assert.Equal(t, tt.want, rec)
+		})
+	}
+}
+
+func TestRequestID_ReachesServiceLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var out bytes.Buffer
+	logger := logging.New(&out, slog.LevelInfo, nil)
+	repo := mocks.NewMockUserRepository(ctrl)
+	repo.EXPECT().DeleteUser(gomock.Any(), uint64(3)).Return(nil)
+	h := NewUserHandler(service.NewUserService(repo, service.WithLogger(logger)))
+
+	r := gin.New()
+	r.Use(RequestID())
+	r.DELETE("/users/:id", h.DeleteUser)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/3", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var rec map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &rec))
+	assert.Equal(t, "user deleted", rec["msg"])
+	assert.Equal(t, 3.0, rec["user_id"])
+	assert.Equal(t, "req-2", rec["request_id"])
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeoutHeader carries how long the caller will wait for the
+// response, as a duration such as "1.5s" or "250ms".
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// Deadline is middleware that bounds how long a request may run. A route's
+// timeout is looked up in routes by method and route template, such as
+// "POST /users/bulk", falling back to def; zero means no limit. A caller may
+// shorten the timeout with X-Request-Timeout but never extend it, and a
+// request whose caller has no time left is answered with 504 straight away.
+//
+// The deadline is set on the request context, so database work still running
+// when it passes is interrupted and reported as a timeout.
+func Deadline(def time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			timeout = def
+		}
+		if upstream, ok := requestTimeout(c.GetHeader(RequestTimeoutHeader)); ok {
+			if upstream <= 0 {
+				writeProblem(c, Problem{Type: ProblemTimeout, Status: http.StatusGatewayTimeout, Detail: "request timed out"})
+				c.Abort()
+				return
+			}
+			if timeout <= 0 || upstream < timeout {
+				timeout = upstream
+			}
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requestTimeout parses an X-Request-Timeout value. Values that do not parse
+// are ignored, like any other malformed hint from the caller.
+func requestTimeout(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
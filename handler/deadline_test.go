@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/mocks"
+	"user-service/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadline(t *testing.T) {
+	remaining := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, time.Until(deadline).Round(100*time.Millisecond).String())
+	}
+	router := func(def time.Duration) *gin.Engine {
+		r := gin.New()
+		r.Use(Deadline(def, map[string]time.Duration{"POST /slow": 3 * time.Second}))
+		r.GET("/fast", remaining)
+		r.POST("/slow", remaining)
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		def      time.Duration
+		method   string
+		target   string
+		upstream string
+		want     string
+	}{
+		{"default timeout", time.Second, http.MethodGet, "/fast", "", "1s"},
+		{"route timeout", time.Second, http.MethodPost, "/slow", "", "3s"},
+		{"caller shortens it", time.Second, http.MethodPost, "/slow", "500ms", "500ms"},
+		{"caller cannot extend it", time.Second, http.MethodGet, "/fast", "1m", "1s"},
+		{"caller sets it when there is no limit", 0, http.MethodGet, "/fast", "2s", "2s"},
+		{"malformed header is ignored", time.Second, http.MethodGet, "/fast", "soon", "1s"},
+		{"no limit", 0, http.MethodGet, "/fast", "", "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.target, nil)
+			if tt.upstream != "" {
+				req.Header.Set(RequestTimeoutHeader, tt.upstream)
+			}
+			w := httptest.NewRecorder()
+			router(tt.def).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+
+	t.Run("caller out of time", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/fast", nil)
+		req.Header.Set(RequestTimeoutHeader, "0s")
+		w := httptest.NewRecorder()
+		router(time.Second).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		var got Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, ProblemTimeout, got.Type)
+	})
+}
+
+func TestDeadline_ExpiredRequestIsATimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Once the deadline passes, the database may fail in ways that do not
+	// mention it; the response is still a timeout rather than a 500.
+	mockSvc := mocks.NewMockUserService(ctrl)
+	mockSvc.EXPECT().GetUser(gomock.Any(), uint64(1)).DoAndReturn(func(ctx context.Context, _ uint64) (model.User, error) {
+		<-ctx.Done()
+		return model.User{}, errors.New("sql: transaction has already been committed or rolled back")
+	})
+
+	h := NewUserHandler(mockSvc)
+	r := gin.New()
+	r.Use(Deadline(20*time.Millisecond, nil))
+	r.GET("/users/:id", h.GetUser)
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	var got Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, ProblemTimeout, got.Type)
+	assert.Equal(t, "request timed out", got.Detail)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"user-service/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths
+// cannot create new series.
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with a non-standard method, for the same reason.
+const otherMethod = "other"
+
+// standardMethods are the methods that label their own series.
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true,
+	http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Metrics is middleware that counts requests in http_requests_total and times
+// them in http_request_duration_seconds, labelled by route template such as
+// /users/:id. Non-standard methods are all labelled "other".
+func Metrics(reg *metrics.Registry) gin.HandlerFunc {
+	requests := reg.NewCounterVec("http_requests_total",
+		"HTTP requests handled, by method, route and status code.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds",
+		"Latency of HTTP requests, by method and route.", metrics.DefBuckets, "method", "route")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		if !standardMethods[method] {
+			method = otherMethod
+		}
+		requests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		duration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
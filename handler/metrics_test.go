@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-service/metrics"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	reg := metrics.NewRegistry()
+	h := NewUserHandler(mockSvc, WithMaxBatchSize(2), WithMetrics(reg))
+
+	r := gin.New()
+	r.Use(Metrics(reg))
+	r.GET("/users/:id", h.GetUser)
+	r.POST("/users/batch", h.BatchFetchUsers)
+
+	mockSvc.EXPECT().GetUser(gomock.Any(), uint64(1)).Return(model.User{ID: 1, Name: "Alice"}, nil)
+	mockSvc.EXPECT().GetUser(gomock.Any(), uint64(2)).Return(model.User{}, service.ErrUserNotFound)
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/users/1", ""},
+		{http.MethodGet, "/users/2", ""},
+		{http.MethodGet, "/no/such/route", ""},
+		{"BREW", "/no/such/route", ""},
+		{"X-RANDOM-1", "/users/1", ""},
+		{http.MethodPost, "/users/batch", `{"user_ids": [1, 2, 3]}`},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+	}
+
+	var out bytes.Buffer
+	_, err := reg.WriteTo(&out)
+	assert.NoError(t, err)
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 1`,
+		`http_requests_total{method="GET",route="/users/:id",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="other",route="unmatched",status="404"} 2`,
+		`http_requests_total{method="POST",route="/users/batch",status="413"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/users/:id"} 2`,
+		`user_batch_fetch_size_bucket{le="2"} 0`,
+		`user_batch_fetch_size_bucket{le="4"} 1`,
+		`user_batch_fetch_size_sum 3`,
+	} {
+		assert.Contains(t, out.String(), line+"\n")
+	}
+	assert.NotContains(t, out.String(), "BREW")
+}
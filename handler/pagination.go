@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"user-service/model"
+)
+
+// pageLinks builds an RFC 8288 Link header value for a listing page. Offset
+// pages get first/prev/next/last links; cursor pages get prev/next links.
+func pageLinks(u *url.URL, page model.UserPage) string {
+	var links []string
+	add := func(rel string, set func(q url.Values)) {
+		q := u.Query()
+		q.Del("cursor")
+		q.Del("page_num")
+		q.Set("page_size", strconv.Itoa(page.PageSize))
+		set(q)
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, u.Path, q.Encode(), rel))
+	}
+	withPage := func(n int) func(url.Values) {
+		return func(q url.Values) { q.Set("page_num", strconv.Itoa(n)) }
+	}
+	withCursor := func(cursor string) func(url.Values) {
+		return func(q url.Values) { q.Set("cursor", cursor) }
+	}
+
+	if page.Page == 0 {
+		if page.PrevCursor != "" {
+			add("prev", withCursor(page.PrevCursor))
+		}
+		if page.NextCursor != "" {
+			add("next", withCursor(page.NextCursor))
+		}
+		return strings.Join(links, ", ")
+	}
+
+	last := lastPage(page.Total, page.PageSize)
+	add("first", withPage(1))
+	if page.Page > 1 {
+		add("prev", withPage(min(page.Page-1, last)))
+	}
+	if page.HasNext {
+		add("next", withPage(page.Page+1))
+	}
+	add("last", withPage(last))
+	return strings.Join(links, ", ")
+}
+
+// lastPage returns the number of the final page, which is 1 for an empty listing.
+func lastPage(total int64, size int) int {
+	if total == 0 || size <= 0 {
+		return 1
+	}
+	return int((total + int64(size) - 1) / int64(size))
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+	"user-service/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		page     model.UserPage
+		expected string
+	}{
+		{
+			name:   "middle page",
+			rawURL: "/users?page_num=2&page_size=10&include_deleted=true",
+			page:   model.UserPage{Total: 35, Page: 2, PageSize: 10, HasNext: true},
+			expected: `</users?include_deleted=true&page_num=1&page_size=10>; rel="first", ` +
+				`</users?include_deleted=true&page_num=1&page_size=10>; rel="prev", ` +
+				`</users?include_deleted=true&page_num=3&page_size=10>; rel="next", ` +
+				`</users?include_deleted=true&page_num=4&page_size=10>; rel="last"`,
+		},
+		{
+			name:   "first and only page",
+			rawURL: "/users",
+			page:   model.UserPage{Total: 3, Page: 1, PageSize: 10},
+			expected: `</users?page_num=1&page_size=10>; rel="first", ` +
+				`</users?page_num=1&page_size=10>; rel="last"`,
+		},
+		{
+			name:   "page past the end",
+			rawURL: "/users?page_num=9&page_size=10",
+			page:   model.UserPage{Total: 15, Page: 9, PageSize: 10},
+			expected: `</users?page_num=1&page_size=10>; rel="first", ` +
+				`</users?page_num=2&page_size=10>; rel="prev", ` +
+				`</users?page_num=2&page_size=10>; rel="last"`,
+		},
+		{
+			name:   "cursor page",
+			rawURL: "/users?cursor=abc&page_size=5",
+			page:   model.UserPage{Total: 15, PageSize: 5, HasNext: true, NextCursor: "n", PrevCursor: "p"},
+			expected: `</users?cursor=p&page_size=5>; rel="prev", ` +
+				`</users?cursor=n&page_size=5>; rel="next"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.rawURL)
+			assert.Equal(t, tt.expected, pageLinks(u, tt.page))
+		})
+	}
+}
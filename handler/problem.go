@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"user-service/model"
+	"user-service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of every error response.
+const ProblemContentType = "application/problem+json"
+
+// Problem type URIs, relative to the service root.
+const (
+	ProblemInvalidRequest       = "/problems/invalid-request"
+	ProblemValidation           = "/problems/validation-error"
+	ProblemBatchTooLarge        = "/problems/batch-too-large"
+	ProblemUnsupportedMediaType = "/problems/unsupported-media-type"
+	ProblemVersionMismatch      = "/problems/version-mismatch"
+	ProblemRetentionExpired     = "/problems/retention-expired"
+	ProblemNotFound             = "/problems/not-found"
+	ProblemUserNotDeleted       = "/problems/user-not-deleted"
+	ProblemConflict             = "/problems/conflict"
+	ProblemTimeout              = "/problems/timeout"
+	ProblemUnavailable          = "/problems/unavailable"
+	ProblemInternal             = "/problems/internal-error"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// Results carries per-item outcomes when a bulk request is rejected.
+	Results []model.BulkCreateResult `json:"results,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected. Field is the
+// JSON path of the value, such as "name" or "users[2].name".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorProblems maps service errors to problems, most specific first. An
+// empty detail means the error text itself is safe to show the client.
+var errorProblems = []struct {
+	err    error
+	status int
+	typ    string
+	detail string
+}{
+	{service.ErrBatchTooLarge, http.StatusRequestEntityTooLarge, ProblemBatchTooLarge, ""},
+	{service.ErrVersionConflict, http.StatusPreconditionFailed, ProblemVersionMismatch, "version mismatch"},
+	{service.ErrRetentionExpired, http.StatusGone, ProblemRetentionExpired, "user can no longer be restored"},
+	{service.ErrUserNotFound, http.StatusNotFound, ProblemNotFound, "user not found"},
+	{service.ErrUserNotDeleted, http.StatusConflict, ProblemUserNotDeleted, "user is not deleted"},
+	{service.ErrInvalidInput, http.StatusBadRequest, ProblemValidation, ""},
+	{service.ErrValidation, http.StatusBadRequest, ProblemValidation, "request violates a data constraint"},
+	{service.ErrNotFound, http.StatusNotFound, ProblemNotFound, "not found"},
+	{service.ErrConflict, http.StatusConflict, ProblemConflict, "conflict"},
+	{service.ErrTimeout, http.StatusGatewayTimeout, ProblemTimeout, "request timed out"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, ProblemTimeout, "request timed out"},
+	{service.ErrUnavailable, http.StatusServiceUnavailable, ProblemUnavailable, "service temporarily unavailable"},
+}
+
+// writeError responds with the problem for a service error, listing the
+// offending field if the error names one. Errors of unknown kind become a
+// 500 whose details stay in the server log, or a 504 if the request's
+// deadline has passed.
+func writeError(c *gin.Context, err error) {
+	_ = c.Error(err)
+
+	for _, e := range errorProblems {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+		detail := e.detail
+		if detail == "" {
+			detail = err.Error()
+		}
+		problem := Problem{Type: e.typ, Status: e.status, Detail: detail}
+		var fieldErr *service.FieldError
+		if errors.As(err, &fieldErr) {
+			problem.Errors = []FieldError{{Field: fieldErr.Field, Message: fieldErr.Message}}
+		}
+		writeProblem(c, problem)
+		return
+	}
+	// Once the deadline has passed, the database driver may report the
+	// abandoned work in ways that do not mention it, such as a transaction
+	// that is already rolled back.
+	if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		writeProblem(c, Problem{Type: ProblemTimeout, Status: http.StatusGatewayTimeout, Detail: "request timed out"})
+		return
+	}
+	writeProblem(c, Problem{Type: ProblemInternal, Status: http.StatusInternalServerError, Detail: "internal server error"})
+}
+
+// invalidParam responds with a validation problem for a single bad request
+// parameter, such as a query string value that does not parse.
+func invalidParam(c *gin.Context, field, message string) {
+	writeProblem(c, Problem{
+		Type:   ProblemValidation,
+		Status: http.StatusBadRequest,
+		Detail: "invalid " + field,
+		Errors: []FieldError{{Field: field, Message: message}},
+	})
+}
+
+// bindError responds with the problem for a request body that failed to bind,
+// listing every offending field when the body parsed but did not validate.
+func bindError(c *gin.Context, err error) {
+	_ = c.Error(err)
+
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)}
+		}
+		writeProblem(c, Problem{
+			Type:   ProblemValidation,
+			Status: http.StatusBadRequest,
+			Detail: "request body failed validation",
+			Errors: fields,
+		})
+	case errors.As(err, &typeErr):
+		writeProblem(c, Problem{
+			Type:   ProblemValidation,
+			Status: http.StatusBadRequest,
+			Detail: "request body failed validation",
+			Errors: []FieldError{{Field: indexPath(typeErr.Field), Message: "must be of type " + jsonType(typeErr.Type)}},
+		})
+	default:
+		writeProblem(c, Problem{Type: ProblemInvalidRequest, Status: http.StatusBadRequest, Detail: "invalid request"})
+	}
+}
+
+// writeProblem fills in the fields every problem shares and writes it.
+func writeProblem(c *gin.Context, p Problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.Instance = c.Request.URL.RequestURI()
+	p.RequestID = RequestIDFrom(c)
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(p.Status, p)
+}
+
+// fieldPath renders a validator namespace such as
+// "BulkCreateUsersRequest.users[2].name" as "users[2].name".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+// indexPath renders a decoder field path such as "users.2.name" as
+// "users[2].name", matching fieldPath.
+func indexPath(field string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		switch {
+		case isIndex(part):
+			b.WriteString("[" + part + "]")
+		case i > 0:
+			b.WriteString("." + part)
+		default:
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldMessage explains a failed validation rule in plain words.
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
+
+// jsonType names the JSON type that decodes into t.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		if t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64 {
+			return "integer"
+		}
+		return t.String()
+	}
+}
+
+var registerJSONFieldNames sync.Once
+
+// useJSONFieldNames makes gin's validator report fields by their JSON names,
+// so field errors match what the client sent.
+func useJSONFieldNames() {
+	registerJSONFieldNames.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	})
+}
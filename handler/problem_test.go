@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/service"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	router := setupRouter(NewUserHandler(mockSvc))
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		mockFunc func()
+		want     Problem
+	}{
+		{
+			name:   "missing required field",
+			method: http.MethodPost,
+			target: "/users",
+			body:   `{}`,
+			want: Problem{
+				Type:   ProblemValidation,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "request body failed validation",
+				Errors: []FieldError{{Field: "name", Message: "is required"}},
+			},
+		},
+		{
+			name:   "malformed JSON",
+			method: http.MethodPost,
+			target: "/users",
+			body:   `{"name":`,
+			want: Problem{
+				Type:   ProblemInvalidRequest,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "invalid request",
+			},
+		},
+		{
+			name:   "bad path parameter",
+			method: http.MethodGet,
+			target: "/users/abc",
+			want: Problem{
+				Type:   ProblemValidation,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "invalid id",
+				Errors: []FieldError{{Field: "id", Message: "must be an unsigned integer"}},
+			},
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			target: "/users/7",
+			mockFunc: func() {
+				mockSvc.EXPECT().GetUser(gomock.Any(), uint64(7)).Return(model.User{}, service.ErrUserNotFound)
+			},
+			want: Problem{
+				Type:   ProblemNotFound,
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "user not found",
+			},
+		},
+		{
+			name:   "unavailable",
+			method: http.MethodGet,
+			target: "/users/8",
+			mockFunc: func() {
+				mockSvc.EXPECT().GetUser(gomock.Any(), uint64(8)).
+					Return(model.User{}, fmt.Errorf("%w: database is locked", service.ErrUnavailable))
+			},
+			want: Problem{
+				Type:   ProblemUnavailable,
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: "service temporarily unavailable",
+			},
+		},
+		{
+			name:   "database constraint hides details",
+			method: http.MethodPost,
+			target: "/users",
+			body:   `{"name":"Ann"}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().CreateUser(gomock.Any(), "Ann").Return(model.User{},
+					fmt.Errorf("%w: CHECK constraint failed: length(trim(`name`)) > 0", service.ErrValidation))
+			},
+			want: Problem{
+				Type:   ProblemValidation,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "request violates a data constraint",
+			},
+		},
+		{
+			name:   "invalid field from service",
+			method: http.MethodPatch,
+			target: "/users/5",
+			body:   `{"name":"..."}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().UpdateUser(gomock.Any(), uint64(5), gomock.Any(), uint64(0)).
+					Return(model.User{}, fmt.Errorf("%w: %w", service.ErrInvalidPatch,
+						&service.FieldError{Field: "name", Message: "must be at most 255 characters"}))
+			},
+			want: Problem{
+				Type:   ProblemValidation,
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "invalid patch: name must be at most 255 characters",
+				Errors: []FieldError{{Field: "name", Message: "must be at most 255 characters"}},
+			},
+		},
+		{
+			name:   "deadline exceeded",
+			method: http.MethodGet,
+			target: "/users/9",
+			mockFunc: func() {
+				mockSvc.EXPECT().GetUser(gomock.Any(), uint64(9)).
+					Return(model.User{}, fmt.Errorf("%w: interrupted", context.DeadlineExceeded))
+			},
+			want: Problem{
+				Type:   ProblemTimeout,
+				Title:  "Gateway Timeout",
+				Status: http.StatusGatewayTimeout,
+				Detail: "request timed out",
+			},
+		},
+		{
+			name:   "internal error hides details",
+			method: http.MethodPost,
+			target: "/users/purge",
+			mockFunc: func() {
+				mockSvc.EXPECT().PurgeDeletedUsers(gomock.Any()).Return(int64(0), errors.New("disk I/O error at /var/lib/db"))
+			},
+			want: Problem{
+				Type:   ProblemInternal,
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "internal server error",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mockFunc != nil {
+				tt.mockFunc()
+			}
+
+			req, _ := http.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(RequestIDHeader, "req-123")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want.Status, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+			var got Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			tt.want.Instance = tt.target
+			tt.want.RequestID = "req-123"
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProblemResponses_BulkFieldPaths(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router := setupRouter(NewUserHandler(mocks.NewMockUserService(ctrl)))
+
+	req, _ := http.NewRequest(http.MethodPost, "/users/bulk", bytes.NewBufferString(`{"users":[{"name":"Ann"},{"name":7}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"errors":[{"field":"users[1].name","message":"must be of type string"}]`)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-service/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		// The ID is reachable from both the gin and the request context.
+		c.String(http.StatusOK, RequestIDFrom(c)+" "+logging.RequestID(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"generated when absent", "", false},
+		{"reused when valid", "abc-123", true},
+		{"replaced when it has spaces", "abc 123", false},
+		{"replaced when too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			assert.Equal(t, id+" "+id, w.Body.String())
+			if tt.reuse {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-service/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tracing is middleware that runs every request in a server span named after
+// its route template, continuing the caller's trace when the request carries
+// a traceparent header. Server errors mark the span as failed.
+func Tracing(tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracer.Start(ctx, method+" "+route,
+			tracing.WithKind(tracing.KindServer),
+			tracing.WithAttributes(
+				tracing.String("http.request.method", method),
+				tracing.String("http.route", route),
+				tracing.String("url.path", c.Request.URL.Path),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if id, ok := c.Get(userIDKey); ok {
+			span.SetAttributes(tracing.Int64("user.id", int64(id.(uint64))))
+		}
+		if status >= 500 {
+			err := c.Errors.Last()
+			if err == nil {
+				span.RecordError(errors.New(http.StatusText(status)))
+			} else {
+				span.RecordError(err.Err)
+			}
+		}
+	}
+}
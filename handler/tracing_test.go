@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// spanRecorder keeps exported spans in memory.
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(d tracing.SpanData) error {
+	r.spans = append(r.spans, d)
+	return nil
+}
+
+func attrs(d tracing.SpanData) map[string]any {
+	m := make(map[string]any, len(d.Attrs))
+	for _, a := range d.Attrs {
+		m[a.Key] = a.Value
+	}
+	return m
+}
+
+func TestTracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	h := NewUserHandler(mockSvc)
+	rec := &spanRecorder{}
+
+	r := gin.New()
+	r.Use(Tracing(tracing.NewTracer(rec)))
+	r.GET("/users/:id", h.GetUser)
+
+	var serviceSpan tracing.SpanContext
+	mockSvc.EXPECT().GetUser(gomock.Any(), uint64(7)).DoAndReturn(func(ctx context.Context, id uint64) (model.User, error) {
+		serviceSpan = tracing.SpanContextFromContext(ctx)
+		return model.User{ID: 7, Name: "Alice"}, nil
+	})
+	mockSvc.EXPECT().GetUser(gomock.Any(), uint64(8)).Return(model.User{}, errors.New("disk on fire"))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/8", nil))
+
+	if !assert.Len(t, rec.spans, 2) {
+		return
+	}
+
+	ok := rec.spans[0]
+	assert.Equal(t, "GET /users/:id", ok.Name)
+	assert.Equal(t, tracing.KindServer, ok.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", ok.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", ok.Parent.String())
+	assert.Equal(t, ok.SpanContext, serviceSpan, "the service runs under the server span")
+	assert.Equal(t, map[string]any{
+		"http.request.method":       "GET",
+		"http.route":                "/users/:id",
+		"url.path":                  "/users/7",
+		"http.response.status_code": int64(http.StatusOK),
+		"user.id":                   int64(7),
+	}, attrs(ok))
+	assert.Empty(t, ok.Err)
+
+	failed := rec.spans[1]
+	assert.NotEqual(t, ok.TraceID, failed.TraceID, "requests without traceparent start a new trace")
+	assert.False(t, failed.Parent.IsValid())
+	assert.Equal(t, int64(http.StatusInternalServerError), attrs(failed)["http.response.status_code"])
+	assert.NotEmpty(t, failed.Err)
+}
@@ -1,22 +1,59 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"user-service/metrics"
 	"user-service/model"
 	"user-service/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DefaultMaxBatchSize is the largest number of IDs accepted by POST /users/batch.
+const DefaultMaxBatchSize = 1000
+
 // UserHandler handles HTTP requests related to users.
 type UserHandler struct {
-	Svc service.UserService
+	Svc          service.UserService
+	MaxBatchSize int
+	batchSizes   *metrics.HistogramVec
+}
+
+// Option customizes a UserHandler.
+type Option func(*UserHandler)
+
+// WithMaxBatchSize sets the largest number of IDs accepted by POST /users/batch.
+func WithMaxBatchSize(size int) Option {
+	return func(h *UserHandler) {
+		h.MaxBatchSize = size
+	}
+}
+
+// WithMetrics records the number of IDs requested from POST /users/batch in
+// the user_batch_fetch_size histogram.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(h *UserHandler) {
+		h.batchSizes = reg.NewHistogramVec("user_batch_fetch_size",
+			"Number of user IDs requested per POST /users/batch.", metrics.ExponentialBuckets(1, 2, 11))
+	}
 }
 
 // NewUserHandler initializes the user handler with service dependency.
-func NewUserHandler(svc service.UserService) *UserHandler {
-	return &UserHandler{Svc: svc}
+func NewUserHandler(svc service.UserService, opts ...Option) *UserHandler {
+	useJSONFieldNames()
+
+	h := &UserHandler{Svc: svc, MaxBatchSize: DefaultMaxBatchSize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CreateUser handles POST /users
@@ -26,64 +63,386 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 	// Bind and validate input
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
 	// Call the service layer
 	user, err := h.Svc.CreateUser(c.Request.Context(), req.Name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		writeError(c, err)
 		return
 	}
+	setUserID(c, user.ID)
 
+	c.Header("ETag", etag(user.Version))
 	c.JSON(http.StatusCreated, gin.H{"result": true, "user": user})
 }
 
+// BulkCreateUsers handles POST /users/bulk
+// Creates many users at once. mode=atomic (default) creates all or none;
+// mode=partial creates the valid items and reports each item's outcome.
+func (h *UserHandler) BulkCreateUsers(c *gin.Context) {
+	var req model.BulkCreateUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	names := make([]string, len(req.Users))
+	for i, u := range req.Users {
+		names[i] = u.Name
+	}
+	mode := model.BulkMode(c.DefaultQuery("mode", string(model.BulkModeAtomic)))
+
+	results, err := h.Svc.CreateUsers(c.Request.Context(), names, mode)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrInvalidUser) && results != nil:
+		writeProblem(c, Problem{
+			Type:    ProblemValidation,
+			Status:  http.StatusUnprocessableEntity,
+			Detail:  err.Error(),
+			Results: results,
+		})
+		return
+	default:
+		writeError(c, err)
+		return
+	}
+
+	created := 0
+	for _, r := range results {
+		if r.Status == model.BulkItemCreated {
+			created++
+		}
+	}
+
+	status := http.StatusCreated
+	if created < len(results) {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, gin.H{
+		"result":  true,
+		"created": created,
+		"failed":  len(results) - created,
+		"results": results,
+	})
+}
+
 // GetUser handles GET /users/:id
 // Returns a user by ID.
 func (h *UserHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"result": false, "error": "invalid id"})
+		invalidParam(c, "id", "must be an unsigned integer")
 		return
 	}
-	user, err := h.Svc.GetUser(c.Request.Context(), id)
+	setUserID(c, id)
+	ctx, err := readContext(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"result": false, "error": "user not found"})
+		invalidParam(c, "include_deleted", "must be a boolean")
 		return
 	}
+	user, err := h.Svc.GetUser(ctx, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("ETag", etag(user.Version))
 	c.JSON(http.StatusOK, gin.H{"result": true, "user": user})
 }
 
 // GetAllUsers handles GET /users
-// Supports pagination via page_num & page_size query parameters.
+// Supports pagination via page_num & page_size query parameters, or keyset
+// pagination via the opaque cursor returned as next_cursor/prev_cursor.
+// Paging metadata is returned in the body and as Link/X-Total-Count headers.
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	pageNum, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	ctx, err := readContext(c)
+	if err != nil {
+		invalidParam(c, "include_deleted", "must be a boolean")
+		return
+	}
 
-	users, err := h.Svc.GetAllUsers(c.Request.Context(), pageNum, pageSize)
+	var page model.UserPage
+	if cursor := c.Query("cursor"); cursor != "" {
+		page, err = h.Svc.ListUsersByCursor(ctx, cursor, pageSize)
+	} else {
+		page, err = h.Svc.GetAllUsers(ctx, pageNum, pageSize)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"result": false, "error": err.Error()})
+		writeError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"result": true, "users": users})
+
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if links := pageLinks(c.Request.URL, page); links != "" {
+		c.Header("Link", links)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"result":      true,
+		"users":       page.Users,
+		"total":       page.Total,
+		"page":        page.Page,
+		"page_size":   page.PageSize,
+		"has_next":    page.HasNext,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+	})
+}
+
+// SearchUsers handles GET /users/search
+// Searches user names via the q query parameter, ranked by relevance and
+// paginated with page_num & page_size. mode=prefix (default) runs a full-text
+// prefix search; mode=fuzzy tolerates typos and scores by name similarity.
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	pageNum, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	ctx, err := readContext(c)
+	if err != nil {
+		invalidParam(c, "include_deleted", "must be a boolean")
+		return
+	}
+
+	mode := model.SearchMode(c.DefaultQuery("mode", string(model.SearchModePrefix)))
+	page, err := h.Svc.SearchUsers(ctx, c.Query("q"), mode, pageNum, pageSize)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	meta := model.UserPage{Total: page.Total, Page: page.Page, PageSize: page.PageSize, HasNext: page.HasNext}
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	c.Header("Link", pageLinks(c.Request.URL, meta))
+	c.JSON(http.StatusOK, gin.H{
+		"result":    true,
+		"results":   page.Matches,
+		"total":     page.Total,
+		"page":      page.Page,
+		"page_size": page.PageSize,
+		"has_next":  page.HasNext,
+	})
 }
 
 // BatchFetchUsers handles POST /users/batch to fetch multiple users by IDs
+// Users come back in request order, de-duplicated, with unknown IDs listed in
+// not_found. With "shape":"map" users are keyed by ID instead.
 func (h *UserHandler) BatchFetchUsers(c *gin.Context) {
 	var req model.BatchFetchUsersRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		bindError(c, err)
+		return
+	}
+
+	if h.batchSizes != nil {
+		h.batchSizes.Observe(float64(len(req.UserIDs)))
+	}
+	if len(req.UserIDs) > h.MaxBatchSize {
+		writeProblem(c, Problem{
+			Type:   ProblemBatchTooLarge,
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("too many user_ids: got %d, at most %d allowed", len(req.UserIDs), h.MaxBatchSize),
+		})
+		return
+	}
+
+	ctx, err := readContext(c)
+	if err != nil {
+		invalidParam(c, "include_deleted", "must be a boolean")
+		return
+	}
+
+	resp, err := h.Svc.GetUsersByIDs(ctx, req.UserIDs)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if req.Shape == "map" {
+		byID := make(map[uint64]model.User, len(resp.Users))
+		for _, user := range resp.Users {
+			byID[user.ID] = user
+		}
+		c.JSON(http.StatusOK, gin.H{"result": true, "users": byID, "not_found": resp.NotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": true, "users": resp.Users, "not_found": resp.NotFound})
+}
+
+// UpdateUser handles PATCH /users/:id
+// Accepts a JSON Merge Patch or JSON Patch body. When an If-Match header is
+// present the update only succeeds if it matches the user's current ETag.
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidParam(c, "id", "must be an unsigned integer")
+		return
+	}
+	setUserID(c, id)
+
+	format, ok := patchFormat(c.ContentType())
+	if !ok {
+		writeProblem(c, Problem{
+			Type:   ProblemUnsupportedMediaType,
+			Status: http.StatusUnsupportedMediaType,
+			Detail: "unsupported patch media type",
+		})
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		writeProblem(c, Problem{Type: ProblemVersionMismatch, Status: http.StatusPreconditionFailed, Detail: "version mismatch"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		writeProblem(c, Problem{Type: ProblemInvalidRequest, Status: http.StatusBadRequest, Detail: "invalid request"})
+		return
+	}
+
+	user, err := h.Svc.UpdateUser(c.Request.Context(), id, model.UserPatch{Format: format, Body: body}, expectedVersion)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, gin.H{"result": true, "user": user})
+}
+
+// DeleteUser handles DELETE /users/:id
+// Soft-deletes a user; it can be restored until the retention window passes.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidParam(c, "id", "must be an unsigned integer")
+		return
+	}
+	setUserID(c, id)
+
+	if err := h.Svc.DeleteUser(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreUser handles POST /users/:id/restore
+// Undoes a soft delete that is still inside the retention window.
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidParam(c, "id", "must be an unsigned integer")
 		return
 	}
+	setUserID(c, id)
 
-	users, err := h.Svc.GetUsersByIDs(c.Request.Context(), req.UserIDs)
+	user, err := h.Svc.RestoreUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"result": true, "users": users})
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, gin.H{"result": true, "user": user})
+}
+
+// PurgeDeletedUsers handles POST /users/purge
+// Permanently removes users whose soft delete is older than the retention window.
+func (h *UserHandler) PurgeDeletedUsers(c *gin.Context) {
+	purged, err := h.Svc.PurgeDeletedUsers(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": true, "purged": purged})
+}
+
+// pageParams parses the page_num and page_size query parameters, defaulting
+// to the first page of 10. It responds with a problem and returns false when
+// either is not an integer.
+func pageParams(c *gin.Context) (pageNum, pageSize int, ok bool) {
+	pageNum, err := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if err != nil {
+		invalidParam(c, "page_num", "must be an integer")
+		return 0, 0, false
+	}
+	pageSize, err = strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil {
+		invalidParam(c, "page_size", "must be an integer")
+		return 0, 0, false
+	}
+	return pageNum, pageSize, true
+}
+
+// readContext returns the request context, widened to include soft-deleted
+// users when the include_deleted query flag is set.
+func readContext(c *gin.Context) (context.Context, error) {
+	ctx := c.Request.Context()
+	raw := c.Query("include_deleted")
+	if raw == "" {
+		return ctx, nil
+	}
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	if include {
+		ctx = service.WithDeleted(ctx)
+	}
+	return ctx, nil
+}
+
+// etag renders a user version as a strong entity tag.
+func etag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// parseIfMatch extracts the expected version from an If-Match header.
+// An empty header or "*" yields 0 (unconditional). The second return value is
+// false when the header cannot possibly match any version.
+func parseIfMatch(header string) (uint64, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	// Weak tags never match under If-Match's strong comparison.
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// patchFormat maps a PATCH Content-Type to the corresponding patch format.
+func patchFormat(contentType string) (model.PatchFormat, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	switch mediaType {
+	case "application/merge-patch+json", "application/json":
+		return model.MergePatch, true
+	case "application/json-patch+json":
+		return model.JSONPatch, true
+	default:
+		return 0, false
+	}
 }
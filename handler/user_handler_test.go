@@ -5,29 +5,54 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"user-service/logging"
 	"user-service/mocks"
 	"user-service/model"
+	"user-service/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
+// testRequestID is the request ID of every request sent through setupRouter
+// that does not bring its own, so mocks can expect the exact context the
+// handler passes on.
+const testRequestID = "test-request"
+
+// requestContext is the context handlers pass to the service for requests
+// tagged with testRequestID.
+func requestContext() context.Context {
+	return logging.WithRequestID(context.Background(), testRequestID)
+}
+
 func setupRouter(h *UserHandler) *gin.Engine {
 	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader(RequestIDHeader) == "" {
+			c.Request.Header.Set(RequestIDHeader, testRequestID)
+		}
+	}, RequestID())
 	r.POST("/users", h.CreateUser)
+	r.GET("/users/search", h.SearchUsers)
 	r.GET("/users/:id", h.GetUser)
 	r.GET("/users", h.GetAllUsers)
 	r.POST("/users/batch", h.BatchFetchUsers)
+	r.POST("/users/bulk", h.BulkCreateUsers)
+	r.POST("/users/purge", h.PurgeDeletedUsers)
+	r.PATCH("/users/:id", h.UpdateUser)
+	r.DELETE("/users/:id", h.DeleteUser)
+	r.POST("/users/:id/restore", h.RestoreUser)
 	return r
 }
 
 func TestCreateUser(t *testing.T) {
-	ctx := context.Background()
+	ctx := requestContext()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -87,8 +112,43 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
+func TestCreateUser_InvalidName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// A real service over a repository that must not be reached.
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	router := setupRouter(NewUserHandler(service.NewUserService(mockRepo)))
+
+	tests := []struct {
+		name    string
+		input   string
+		message string
+	}{
+		{"blank", "   ", "is required"},
+		{"too long", strings.Repeat("a", service.MaxNameLength+1), "must be at most 255 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodyBytes, _ := json.Marshal(gin.H{"name": tt.input})
+			req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var got Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, ProblemValidation, got.Type)
+			assert.Equal(t, "invalid user: name "+tt.message, got.Detail)
+			assert.Equal(t, []FieldError{{Field: "name", Message: tt.message}}, got.Errors)
+		})
+	}
+}
+
 func TestGetUser(t *testing.T) {
-	ctx := context.Background()
+	ctx := requestContext()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -126,10 +186,56 @@ func TestGetUser(t *testing.T) {
 			mockFunc: func() {
 				mockSvc.EXPECT().
 					GetUser(ctx, uint64(10)).
-					Return(model.User{}, errors.New("not found"))
+					Return(model.User{}, service.ErrUserNotFound)
 			},
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:    "database unavailable",
+			paramID: "11",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUser(ctx, uint64(11)).
+					Return(model.User{}, fmt.Errorf("%w: database is locked", service.ErrUnavailable))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:    "database timeout",
+			paramID: "12",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUser(ctx, uint64(12)).
+					Return(model.User{}, fmt.Errorf("%w: context deadline exceeded", service.ErrTimeout))
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:    "unexpected error",
+			paramID: "13",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUser(ctx, uint64(13)).
+					Return(model.User{}, errors.New("disk on fire"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:    "include deleted",
+			paramID: "2?include_deleted=true",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUser(service.WithDeleted(ctx), uint64(2)).
+					Return(model.User{ID: 2, Name: "Bob"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid include deleted",
+			paramID:        "2?include_deleted=maybe",
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -146,7 +252,7 @@ func TestGetUser(t *testing.T) {
 }
 
 func TestGetAllUsers(t *testing.T) {
-	ctx := context.Background()
+	ctx := requestContext()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -156,29 +262,87 @@ func TestGetAllUsers(t *testing.T) {
 
 	tests := []struct {
 		name           string
+		query          string
 		mockFunc       func()
 		expectedStatus int
+		expectedBody   string
+		expectedTotal  string
+		expectedLink   string
 	}{
 		{
 			name: "success get all",
 			mockFunc: func() {
 				mockSvc.EXPECT().
 					GetAllUsers(ctx, 1, 10).
-					Return([]model.User{
-						{ID: 1, Name: "Alice"},
-						{ID: 2, Name: "Bob"},
+					Return(model.UserPage{
+						Users: []model.User{
+							{ID: 1, Name: "Alice"},
+							{ID: 2, Name: "Bob"},
+						},
+						Total:      12,
+						Page:       1,
+						PageSize:   10,
+						HasNext:    true,
+						NextCursor: "next",
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"next_cursor":"next"`,
+			expectedTotal:  "12",
+			expectedLink:   `</users?page_num=2&page_size=10>; rel="next"`,
+		},
+		{
+			name:  "cursor pagination",
+			query: "?cursor=abc&page_size=5",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					ListUsersByCursor(ctx, "abc", 5).
+					Return(model.UserPage{
+						Users:      []model.User{{ID: 3, Name: "Carol"}},
+						PrevCursor: "prev",
 					}, nil)
 			},
 			expectedStatus: http.StatusOK,
+			expectedBody:   `"prev_cursor":"prev"`,
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=forged",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					ListUsersByCursor(ctx, "forged", 10).
+					Return(model.UserPage{}, service.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"detail":"invalid cursor"`,
+		},
+		{
+			name:           "non-numeric page size",
+			query:          "?page_size=ten",
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"errors":[{"field":"page_size","message":"must be an integer"}]`,
+		},
+		{
+			name:  "out of range page",
+			query: "?page_num=-1",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetAllUsers(ctx, -1, 10).
+					Return(model.UserPage{}, service.ErrInvalidPagination)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"type":"/problems/validation-error"`,
 		},
 		{
 			name: "internal server error",
 			mockFunc: func() {
 				mockSvc.EXPECT().
 					GetAllUsers(ctx, 1, 10).
-					Return(nil, errors.New("db error"))
+					Return(model.UserPage{}, errors.New("db error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"type":"/problems/internal-error"`,
 		},
 	}
 
@@ -186,17 +350,22 @@ func TestGetAllUsers(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc()
 
-			req, _ := http.NewRequest(http.MethodGet, "/users", nil)
+			req, _ := http.NewRequest(http.MethodGet, "/users"+tt.query, nil)
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			if tt.expectedTotal != "" {
+				assert.Equal(t, tt.expectedTotal, w.Header().Get("X-Total-Count"))
+			}
+			assert.Contains(t, w.Header().Get("Link"), tt.expectedLink)
 		})
 	}
 }
 
 func TestBatchFetchUsers(t *testing.T) {
-	ctx := context.Background()
+	ctx := requestContext()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -217,20 +386,58 @@ func TestBatchFetchUsers(t *testing.T) {
 			mockFunc: func() {
 				mockSvc.EXPECT().
 					GetUsersByIDs(ctx, []uint64{1, 2}).
-					Return([]model.User{
-						{ID: 1, Name: "Alice"},
-						{ID: 2, Name: "Bob"},
+					Return(model.BatchFetchUsersResponse{
+						Users: []model.User{
+							{ID: 1, Name: "Alice"},
+							{ID: 2, Name: "Bob"},
+						},
+						NotFound: []uint64{},
 					}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `"result":true`, // partial match
 		},
+		{
+			name:        "success with missing IDs",
+			requestBody: `{"user_ids": [2, 9]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUsersByIDs(ctx, []uint64{2, 9}).
+					Return(model.BatchFetchUsersResponse{
+						Users:    []model.User{{ID: 2, Name: "Bob"}},
+						NotFound: []uint64{9},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"not_found":[9]`,
+		},
+		{
+			name:        "map shape",
+			requestBody: `{"user_ids": [2, 9], "shape": "map"}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					GetUsersByIDs(ctx, []uint64{2, 9}).
+					Return(model.BatchFetchUsersResponse{
+						Users:    []model.User{{ID: 2, Name: "Bob"}},
+						NotFound: []uint64{9},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"users":{"2":{"id":2,"name":"Bob"`,
+		},
+		{
+			name:           "unknown shape",
+			requestBody:    `{"user_ids": [2], "shape": "tree"}`,
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"errors":[{"field":"shape","message":"must be one of: list, map"}]`,
+		},
 		{
 			name:           "invalid request body",
 			requestBody:    `{"user_ids": "not-an-array"}`,
 			mockFunc:       func() {}, // no mock expected
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `"error":"invalid request"`,
+			expectedBody:   `"errors":[{"field":"user_ids","message":"must be of type array"}]`,
 		},
 		{
 			name:        "internal server error",
@@ -238,10 +445,10 @@ func TestBatchFetchUsers(t *testing.T) {
 			mockFunc: func() {
 				mockSvc.EXPECT().
 					GetUsersByIDs(gomock.Any(), []uint64{1, 2}).
-					Return(nil, errors.New("something went wrong"))
+					Return(model.BatchFetchUsersResponse{}, errors.New("something went wrong"))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `"error":"something went wrong"`,
+			expectedBody:   `"detail":"internal server error"`,
 		},
 	}
 
@@ -260,3 +467,504 @@ func TestBatchFetchUsers(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateUser(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		ifMatch        string
+		body           string
+		mockFunc       func()
+		expectedStatus int
+		expectedETag   string
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"3"`,
+			body:        `{"name":"Alicia"}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					UpdateUser(ctx, uint64(1), model.UserPatch{Format: model.MergePatch, Body: []byte(`{"name":"Alicia"}`)}, uint64(3)).
+					Return(model.User{ID: 1, Name: "Alicia", Version: 4}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+		},
+		{
+			name:        "json patch without if-match",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/name","value":"Ally"}]`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					UpdateUser(ctx, uint64(1), model.UserPatch{Format: model.JSONPatch, Body: []byte(`[{"op":"replace","path":"/name","value":"Ally"}]`)}, uint64(0)).
+					Return(model.User{ID: 1, Name: "Ally", Version: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+		},
+		{
+			name:        "stale version",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1"`,
+			body:        `{"name":"Alicia"}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					UpdateUser(ctx, uint64(1), gomock.Any(), uint64(1)).
+					Return(model.User{}, service.ErrVersionConflict)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "weak etag never matches",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `W/"3"`,
+			body:           `{"name":"Alicia"}`,
+			mockFunc:       func() {},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:        "invalid patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"id":2}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					UpdateUser(ctx, uint64(1), gomock.Any(), uint64(0)).
+					Return(model.User{}, service.ErrInvalidPatch)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "user not found",
+			contentType: "application/merge-patch+json",
+			body:        `{"name":"Alicia"}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					UpdateUser(ctx, uint64(1), gomock.Any(), uint64(0)).
+					Return(model.User{}, service.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unsupported media type",
+			contentType:    "text/plain",
+			body:           `name=Alicia`,
+			mockFunc:       func() {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name           string
+		paramID        string
+		mockFunc       func()
+		expectedStatus int
+	}{
+		{
+			name:    "success",
+			paramID: "1",
+			mockFunc: func() {
+				mockSvc.EXPECT().DeleteUser(ctx, uint64(1)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "user not found",
+			paramID: "2",
+			mockFunc: func() {
+				mockSvc.EXPECT().DeleteUser(ctx, uint64(2)).Return(service.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid ID param",
+			paramID:        "abc",
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodDelete, "/users/"+tt.paramID, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name           string
+		mockFunc       func()
+		expectedStatus int
+	}{
+		{
+			name: "success",
+			mockFunc: func() {
+				mockSvc.EXPECT().RestoreUser(ctx, uint64(1)).Return(model.User{ID: 1, Name: "Alice", Version: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not deleted",
+			mockFunc: func() {
+				mockSvc.EXPECT().RestoreUser(ctx, uint64(1)).Return(model.User{}, service.ErrUserNotDeleted)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "retention expired",
+			mockFunc: func() {
+				mockSvc.EXPECT().RestoreUser(ctx, uint64(1)).Return(model.User{}, service.ErrRetentionExpired)
+			},
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name: "user not found",
+			mockFunc: func() {
+				mockSvc.EXPECT().RestoreUser(ctx, uint64(1)).Return(model.User{}, service.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodPost, "/users/1/restore", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name           string
+		mockFunc       func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			mockFunc: func() {
+				mockSvc.EXPECT().PurgeDeletedUsers(ctx).Return(int64(4), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"purged":4`,
+		},
+		{
+			name: "internal server error",
+			mockFunc: func() {
+				mockSvc.EXPECT().PurgeDeletedUsers(ctx).Return(int64(0), errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"type":"/problems/internal-error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodPost, "/users/purge", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockFunc       func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			query: "?q=jo&page_size=1",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					SearchUsers(ctx, "jo", model.SearchModePrefix, 1, 1).
+					Return(model.UserSearchPage{
+						Matches:  []model.UserMatch{{User: model.User{ID: 1, Name: "John"}, Score: 2.5}},
+						Total:    2,
+						Page:     1,
+						PageSize: 1,
+						HasNext:  true,
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"results":[{"user":{"id":1,"name":"John","created_at":0,"updated_at":0,"version":0},"score":2.5}]`,
+		},
+		{
+			name:  "fuzzy mode",
+			query: "?q=jon%20doe&mode=fuzzy",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					SearchUsers(ctx, "jon doe", model.SearchModeFuzzy, 1, 10).
+					Return(model.UserSearchPage{
+						Matches:  []model.UserMatch{{User: model.User{ID: 1, Name: "John Doe"}, Score: 0.55}},
+						Total:    1,
+						Page:     1,
+						PageSize: 10,
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"score":0.55`,
+		},
+		{
+			name:           "non-integer page",
+			query:          "?q=jo&page_num=two",
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"errors":[{"field":"page_num","message":"must be an integer"}]`,
+		},
+		{
+			name:  "missing query",
+			query: "",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					SearchUsers(ctx, "", model.SearchModePrefix, 1, 10).
+					Return(model.UserSearchPage{}, service.ErrInvalidQuery)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"type":"/problems/validation-error"`,
+		},
+		{
+			name:  "internal server error",
+			query: "?q=jo",
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					SearchUsers(ctx, "jo", model.SearchModePrefix, 1, 10).
+					Return(model.UserSearchPage{}, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"type":"/problems/internal-error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodGet, "/users/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestBulkCreateUsers(t *testing.T) {
+	ctx := requestContext()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	handler := NewUserHandler(mockSvc)
+	router := setupRouter(handler)
+
+	alice := model.User{ID: 1, Name: "Alice"}
+
+	tests := []struct {
+		name           string
+		query          string
+		requestBody    string
+		mockFunc       func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "atomic success",
+			requestBody: `{"users":[{"name":"Alice"}]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					CreateUsers(ctx, []string{"Alice"}, model.BulkModeAtomic).
+					Return([]model.BulkCreateResult{{Index: 0, Status: model.BulkItemCreated, User: &alice}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"created":1`,
+		},
+		{
+			name:        "partial with failures",
+			query:       "?mode=partial",
+			requestBody: `{"users":[{"name":"Alice"},{"name":""}]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					CreateUsers(ctx, []string{"Alice", ""}, model.BulkModePartial).
+					Return([]model.BulkCreateResult{
+						{Index: 0, Status: model.BulkItemCreated, User: &alice},
+						{Index: 1, Status: model.BulkItemFailed, Error: "name is required"},
+					}, nil)
+			},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `{"index":1,"status":"failed","error":"name is required"}`,
+		},
+		{
+			name:        "atomic with invalid items",
+			requestBody: `{"users":[{"name":"Alice"},{"name":""}]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					CreateUsers(ctx, []string{"Alice", ""}, model.BulkModeAtomic).
+					Return([]model.BulkCreateResult{
+						{Index: 0, Status: model.BulkItemSkipped},
+						{Index: 1, Status: model.BulkItemFailed, Error: "name is required"},
+					}, service.ErrInvalidUser)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `"status":"skipped"`,
+		},
+		{
+			name:        "too many users",
+			requestBody: `{"users":[{"name":"Alice"}]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					CreateUsers(ctx, []string{"Alice"}, model.BulkModeAtomic).
+					Return(nil, service.ErrBatchTooLarge)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   `"type":"/problems/batch-too-large"`,
+		},
+		{
+			name:           "missing users",
+			requestBody:    `{}`,
+			mockFunc:       func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"errors":[{"field":"users","message":"is required"}]`,
+		},
+		{
+			name:        "internal server error",
+			requestBody: `{"users":[{"name":"Alice"}]}`,
+			mockFunc: func() {
+				mockSvc.EXPECT().
+					CreateUsers(ctx, []string{"Alice"}, model.BulkModeAtomic).
+					Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"detail":"internal server error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req, _ := http.NewRequest(http.MethodPost, "/users/bulk"+tt.query, strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestBatchFetchUsers_MaxBatchSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	router := setupRouter(NewUserHandler(mockSvc, WithMaxBatchSize(2)))
+
+	mockSvc.EXPECT().
+		GetUsersByIDs(gomock.Any(), []uint64{1, 2}).
+		Return(model.BatchFetchUsersResponse{Users: []model.User{}, NotFound: []uint64{1, 2}}, nil)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"at the limit", `{"user_ids": [1, 2]}`, http.StatusOK, `"not_found":[1,2]`},
+		{"over the limit", `{"user_ids": [1, 2, 3]}`, http.StatusRequestEntityTooLarge, `"detail":"too many user_ids: got 3, at most 2 allowed"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/users/batch", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
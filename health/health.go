@@ -0,0 +1,203 @@
+// Package health reports whether the service is alive, ready for traffic and
+// done starting, for use by orchestrator probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Probe selects which set of checks to run.
+type Probe int
+
+const (
+	// Liveness checks whether the process should be restarted.
+	Liveness Probe = iota
+	// Readiness checks whether the process should receive traffic.
+	Readiness
+	// Startup checks whether the process has finished starting.
+	Startup
+)
+
+// Check reports a problem by returning an error. ctx is cancelled after the
+// registry's check timeout, at which point the check is reported as failed
+// whether or not it has returned.
+type Check func(ctx context.Context) error
+
+// Check statuses.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// defaultCheckTimeout bounds each check unless WithCheckTimeout overrides it.
+const defaultCheckTimeout = 2 * time.Second
+
+var (
+	errStarting = errors.New("still starting")
+	errDraining = errors.New("draining")
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a probe. Its status fails if any check failed.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Option customizes a Registry.
+type Option func(*Registry)
+
+// WithCheckTimeout bounds how long a single check may run.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry holds the checks behind each probe. Readiness and startup fail
+// until MarkStarted is called, and readiness fails again after MarkDraining.
+// It is safe for concurrent use.
+type Registry struct {
+	timeout  time.Duration
+	started  atomic.Bool
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[Probe][]namedCheck
+}
+
+// NewRegistry creates a registry with only the built-in checks.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		timeout: defaultCheckTimeout,
+		checks:  make(map[Probe][]namedCheck),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.Register(Startup, "startup", r.checkStarted)
+	r.Register(Readiness, "startup", r.checkStarted)
+	r.Register(Readiness, "draining", r.checkDraining)
+	return r
+}
+
+// Register adds a named check to a probe. Checks run in parallel and are
+// reported in the order they were registered.
+func (r *Registry) Register(p Probe, name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[p] = append(r.checks[p], namedCheck{name, check})
+}
+
+// MarkStarted records that startup has finished.
+func (r *Registry) MarkStarted() {
+	r.started.Store(true)
+}
+
+// MarkDraining records that the process is shutting down and should get no
+// new traffic.
+func (r *Registry) MarkDraining() {
+	r.draining.Store(true)
+}
+
+func (r *Registry) checkStarted(context.Context) error {
+	if !r.started.Load() {
+		return errStarting
+	}
+	return nil
+}
+
+func (r *Registry) checkDraining(context.Context) error {
+	if r.draining.Load() {
+		return errDraining
+	}
+	return nil
+}
+
+// Run runs every check registered for p.
+func (r *Registry) Run(ctx context.Context, p Probe) Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks[p]...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := Result{
+		Name:      c.name,
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Handler serves the report for p as JSON, with status 200 if every check
+// passed and 503 otherwise.
+func (r *Registry) Handler(p Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), p)
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
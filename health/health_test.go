@@ -0,0 +1,130 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func probe(t *testing.T, reg *health.Registry, p health.Probe) (int, health.Report) {
+	rec := httptest.NewRecorder()
+	reg.Handler(p).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+// statuses maps check names to their status.
+func statuses(report health.Report) map[string]string {
+	m := make(map[string]string)
+	for _, res := range report.Checks {
+		m[res.Name] = res.Status
+	}
+	return m
+}
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	reg := health.NewRegistry()
+	reg.Register(health.Readiness, "database", func(context.Context) error { return nil })
+
+	tests := []struct {
+		name       string
+		transition func()
+		probe      health.Probe
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "alive while starting",
+			probe:      health.Liveness,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "not started",
+			probe:      health.Startup,
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"startup": "fail"},
+		},
+		{
+			name:       "not ready while starting",
+			probe:      health.Readiness,
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"startup": "fail", "draining": "ok", "database": "ok"},
+		},
+		{
+			name:       "ready once started",
+			transition: reg.MarkStarted,
+			probe:      health.Readiness,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{"startup": "ok", "draining": "ok", "database": "ok"},
+		},
+		{
+			name:       "started",
+			probe:      health.Startup,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{"startup": "ok"},
+		},
+		{
+			name:       "not ready while draining",
+			transition: reg.MarkDraining,
+			probe:      health.Readiness,
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"startup": "ok", "draining": "fail", "database": "ok"},
+		},
+		{
+			name:       "alive while draining",
+			probe:      health.Liveness,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.transition != nil {
+				tt.transition()
+			}
+			code, report := probe(t, reg, tt.probe)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantChecks, statuses(report))
+		})
+	}
+}
+
+func TestRegistry_FailingChecks(t *testing.T) {
+	reg := health.NewRegistry(health.WithCheckTimeout(20 * time.Millisecond))
+	reg.MarkStarted()
+	reg.Register(health.Readiness, "database", func(context.Context) error {
+		return errors.New("database is locked")
+	})
+	release := make(chan struct{})
+	defer close(release)
+	reg.Register(health.Readiness, "hung", func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	code, report := probe(t, reg, health.Readiness)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, report.Status)
+
+	names := make([]string, len(report.Checks))
+	for i, res := range report.Checks {
+		names[i] = res.Name
+	}
+	assert.Equal(t, []string{"startup", "draining", "database", "hung"}, names)
+
+	assert.Equal(t, "database is locked", report.Checks[2].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[3].Error)
+	assert.GreaterOrEqual(t, report.Checks[3].LatencyMS, 20.0)
+}
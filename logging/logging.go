@@ -0,0 +1,86 @@
+// Package logging builds the service's structured logger. Records are written
+// as JSON, tagged with the request ID carried by their context, and scrubbed
+// of sensitive attributes.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the value of every redacted attribute.
+const Redacted = "[REDACTED]"
+
+// RequestIDKey is the attribute holding the request ID.
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log records carry id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New returns a logger that writes JSON records at level or above to w. Any
+// attribute, at any depth, whose key matches one of redact case-insensitively
+// has its value replaced by Redacted. Records logged with a context carrying
+// a request ID get a request_id attribute.
+func New(w io.Writer, level slog.Level, redact []string) *slog.Logger {
+	redacted := make(map[string]bool, len(redact))
+	for _, key := range redact {
+		redacted[strings.ToLower(key)] = true
+	}
+
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if redacted[strings.ToLower(a.Key)] {
+				return slog.String(a.Key, Redacted)
+			}
+			return a
+		},
+	})
+	return slog.New(contextHandler{h})
+}
+
+// ParseLevel converts a configured level name such as "warn" to a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+// Millis converts d to fractional milliseconds, the unit of every duration
+// attribute the service logs.
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// contextHandler adds the request ID from the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
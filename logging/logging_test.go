@@ -0,0 +1,94 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"user-service/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// record logs one message with log and decodes the JSON it wrote.
+func record(t *testing.T, log func(l *slog.Logger), level slog.Level, redact ...string) map[string]any {
+	var out bytes.Buffer
+	log(logging.New(&out, level, redact))
+	if out.Len() == 0 {
+		return nil
+	}
+
+	var rec map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &rec))
+	return rec
+}
+
+func TestNew(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	tests := []struct {
+		name   string
+		level  slog.Level
+		redact []string
+		log    func(l *slog.Logger)
+		want   map[string]any
+	}{
+		{
+			name:  "request ID from context",
+			level: slog.LevelInfo,
+			log:   func(l *slog.Logger) { l.InfoContext(ctx, "hello", "user_id", 7) },
+			want:  map[string]any{"msg": "hello", "user_id": 7.0, "request_id": "req-1"},
+		},
+		{
+			name:  "no request ID without context",
+			level: slog.LevelInfo,
+			log:   func(l *slog.Logger) { l.Info("hello") },
+			want:  map[string]any{"msg": "hello"},
+		},
+		{
+			name:   "redacts matching keys at any depth",
+			level:  slog.LevelInfo,
+			redact: []string{"token", "Password"},
+			log: func(l *slog.Logger) {
+				l.With("TOKEN", "abc").Info("login",
+					"password", "hunter2",
+					slog.Group("query", "token", "xyz", "q", "ann"))
+			},
+			want: map[string]any{
+				"msg":      "login",
+				"TOKEN":    logging.Redacted,
+				"password": logging.Redacted,
+				"query":    map[string]any{"token": logging.Redacted, "q": "ann"},
+			},
+		},
+		{
+			name:  "below level",
+			level: slog.LevelWarn,
+			log:   func(l *slog.Logger) { l.InfoContext(ctx, "hello") },
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, tt.log, tt.level, tt.redact...)
+			if tt.want == nil {
+				assert.Nil(t, rec)
+				return
+			}
+			delete(rec, "time")
+			delete(rec, "level")
+			assert.Equal(t, tt.want, rec)
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := logging.ParseLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = logging.ParseLevel("trace")
+	assert.Error(t, err)
+}
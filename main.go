@@ -1,32 +1,344 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"user-service/config"
 	"user-service/db"
 	"user-service/handler"
+	"user-service/health"
+	"user-service/logging"
+	"user-service/metrics"
 	"user-service/repository"
+	"user-service/server"
 	"user-service/service"
+	"user-service/tracing"
 
 	"github.com/gin-gonic/gin"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // main initializes dependencies and runs the HTTP server for the user service.
 func main() {
-	gormDB, err := db.InitDB("user.db")
+	args := os.Args[1:]
+	var migrateCmd string
+	if len(args) > 0 && args[0] == "migrate" {
+		if len(args) < 2 || (args[1] != "up" && args[1] != "down" && args[1] != "status") {
+			fmt.Fprintln(os.Stderr, "migrate needs a command: up, down or status")
+			os.Exit(2)
+		}
+		migrateCmd, args = args[1], args[2:]
+	}
+
+	cfg, opts, err := config.Load(args, os.LookupEnv)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		config.Usage(os.Stdout)
+		return
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "run with -h for usage")
+		os.Exit(2)
+	}
+
+	if opts.PrintConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
 
+	level, err := logging.ParseLevel(cfg.Log.Level)
 	if err != nil {
 		panic(err)
 	}
+	logger := logging.New(os.Stderr, level, cfg.Log.Redact)
+	slog.SetDefault(logger)
+
+	if migrateCmd != "" {
+		if err := runMigrate(migrateCmd, cfg, logger); err != nil {
+			logger.Error("migrate "+migrateCmd+" failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var tracer *tracing.Tracer
+	if cfg.Tracing.Output != "" {
+		exporter, err := tracing.OpenFileExporter(cfg.Tracing.Output, "user-service")
+		if err != nil {
+			panic(err)
+		}
+		defer exporter.Close()
+		tracer = tracing.NewTracer(exporter, tracing.WithSampleRatio(cfg.Tracing.SampleRatio))
+	}
 
-	userRepo := repository.NewUserRepo(gormDB)
-	userSvc := service.NewUserService(userRepo)
-	userHandler := handler.NewUserHandler(userSvc)
+	dbOpts := []db.Option{
+		db.WithLogger(db.NewLogger(logger, gormLogLevel(cfg.Log.Level))),
+		db.WithMaxOpenConns(cfg.Database.MaxOpenConns),
+		db.WithMaxIdleConns(cfg.Database.MaxIdleConns),
+		db.WithConnMaxLifetime(cfg.Database.ConnMaxLifetime.Std()),
+		db.WithConnMaxIdleTime(cfg.Database.ConnMaxIdleTime.Std()),
+		db.WithMigrateMode(migrateMode(cfg.Database.Migrate)),
+	}
+	dbOpts = append(dbOpts, connectionOptions(cfg.Database)...)
+	if tracer != nil {
+		dbOpts = append(dbOpts, db.WithTracer(tracer))
+	}
+	gormDB, err := db.InitDB(cfg.Database.Path, dbOpts...)
+	if err != nil {
+		logger.Error("open database", "path", cfg.Database.Path, "error", err)
+		os.Exit(1)
+	}
 
-	r := gin.Default()
+	pools, err := db.Pools(gormDB)
+	if err != nil {
+		panic(err)
+	}
+	reg := metrics.NewRegistry()
+	poolStats := make(map[string]func() sql.DBStats, len(pools))
+	for name, pool := range pools {
+		poolStats[name] = pool.Stats
+	}
+	reg.RegisterDBStats(poolStats)
+
+	var userRepo repository.UserRepository = repository.NewUserRepo(gormDB,
+		repository.WithParallelReads(cfg.Database.ParallelReads))
+	if cfg.Database.CreateBatchSize > 1 {
+		userRepo = repository.NewBatchingRepo(userRepo,
+			repository.WithMaxBatchSize(cfg.Database.CreateBatchSize),
+			repository.WithMaxBatchWait(cfg.Database.CreateBatchWait.Std()),
+			repository.WithBatchMetrics(reg))
+	}
+	userRepo = repository.NewInstrumentedRepo(userRepo, reg)
+	if cfg.Cache.Size > 0 {
+		userRepo = repository.NewCachingRepo(userRepo,
+			repository.WithCacheSize(cfg.Cache.Size),
+			repository.WithCacheTTL(cfg.Cache.TTL.Std()),
+			repository.WithNegativeCacheTTL(cfg.Cache.NegativeTTL.Std()),
+			repository.WithCacheMetrics(reg))
+	}
+	svcOpts := []service.Option{
+		service.WithLogger(logger),
+		service.WithRetention(cfg.Users.Retention.Std()),
+		service.WithMaxPageSize(cfg.Pagination.MaxPageSize),
+		service.WithMaxBulkSize(cfg.Batch.MaxBulkUsers),
+		service.WithFuzzyThreshold(cfg.Search.FuzzyThreshold),
+	}
+	if cfg.Pagination.CursorSecret != "" {
+		svcOpts = append(svcOpts, service.WithCursorSecret([]byte(cfg.Pagination.CursorSecret)))
+	} else {
+		logger.Warn("pagination.cursor_secret is not set; cursors will not survive a restart or work across instances")
+	}
+	if cfg.Loader.BatchSize > 0 {
+		svcOpts = append(svcOpts, service.WithUserLoader(cfg.Loader.BatchWait.Std(), cfg.Loader.BatchSize))
+	}
+	userSvc := service.NewUserService(userRepo, svcOpts...)
+	if tracer != nil {
+		userSvc = service.NewTracedService(userSvc, tracer)
+	}
+	userHandler := handler.NewUserHandler(userSvc,
+		handler.WithMaxBatchSize(cfg.Batch.MaxIDs),
+		handler.WithMetrics(reg))
+
+	checks := health.NewRegistry()
+	checks.Register(health.Readiness, "database", func(ctx context.Context) error {
+		return db.Ping(ctx, gormDB)
+	})
+	checks.Register(health.Readiness, "migrations", func(ctx context.Context) error {
+		return db.CheckSchema(ctx, gormDB)
+	})
+
+	gin.SetMode(cfg.Server.GinMode)
+	r := gin.New()
+	r.Use(gin.Recovery(), handler.RequestID())
+	if tracer != nil {
+		r.Use(handler.Tracing(tracer))
+	}
+	r.Use(handler.AccessLog(logger), handler.Metrics(reg),
+		handler.Deadline(cfg.Server.RequestTimeout.Std(), routeTimeouts(cfg.Server.RouteTimeouts)))
+
+	r.GET("/healthz", gin.WrapH(checks.Handler(health.Liveness)))
+	r.GET("/readyz", gin.WrapH(checks.Handler(health.Readiness)))
+	r.GET("/startupz", gin.WrapH(checks.Handler(health.Startup)))
+	r.GET("/metrics", gin.WrapH(reg.Handler()))
 
 	r.GET("/users", userHandler.GetAllUsers)
+	r.GET("/users/search", userHandler.SearchUsers)
 	r.GET("/users/:id", userHandler.GetUser)
 	r.POST("/users/batch", userHandler.BatchFetchUsers)
+	r.POST("/users/bulk", userHandler.BulkCreateUsers)
 	r.POST("/users", userHandler.CreateUser)
+	r.POST("/users/purge", userHandler.PurgeDeletedUsers)
+	r.PATCH("/users/:id", userHandler.UpdateUser)
+	r.DELETE("/users/:id", userHandler.DeleteUser)
+	r.POST("/users/:id/restore", userHandler.RestoreUser)
+
+	srv := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout.Std(),
+		WriteTimeout: cfg.Server.WriteTimeout.Std(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Std(),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := server.Listen(cfg.Server.Addr)
+	if err != nil {
+		logger.Error("listen failed", "addr", cfg.Server.Addr, "error", err)
+		os.Exit(1)
+	}
+	logger.Info("listening", "addr", ln.Addr().String())
+
+	ctx, handedOff := context.WithCancel(ctx)
+	context.AfterFunc(ctx, checks.MarkDraining)
+	go upgradeOnSignal(logger, ln, cfg.Server.UpgradeTimeout.Std(), handedOff)
+	checks.MarkStarted()
+	if err := server.Ready(); err != nil {
+		logger.Warn("report ready to previous process", "error", err)
+	}
+
+	serveErr := server.Serve(ctx, srv, ln, cfg.Server.ShutdownTimeout.Std())
+	stop()
+	if serveErr != nil {
+		logger.Error("server stopped", "error", serveErr)
+	} else {
+		logger.Info("server stopped, all requests drained")
+	}
+
+	if err := db.Close(gormDB); err != nil {
+		logger.Error("close database", "error", err)
+		os.Exit(1)
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
+}
+
+// upgradeOnSignal re-executes the binary on ln when an upgrade signal
+// arrives and, once the new process is serving, cancels handedOff so this
+// one drains and exits. A failed upgrade leaves this process serving.
+func upgradeOnSignal(logger *slog.Logger, ln net.Listener, readyTimeout time.Duration, handedOff context.CancelFunc) {
+	if len(server.UpgradeSignals) == 0 {
+		return
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, server.UpgradeSignals...)
+	defer signal.Stop(sigs)
 
-	_ = r.Run(":6001")
+	for sig := range sigs {
+		logger.Info("starting new process", "signal", sig.String())
+		pid, err := server.Upgrade(ln, readyTimeout)
+		if err != nil {
+			logger.Error("upgrade failed, still serving", "error", err)
+			continue
+		}
+		logger.Info("new process took over, draining", "pid", pid)
+		handedOff()
+		return
+	}
+}
+
+// connectionOptions applies the SQLite settings every connection needs.
+func connectionOptions(cfg config.DatabaseConfig) []db.Option {
+	opts := []db.Option{
+		db.WithJournalMode(cfg.JournalMode),
+		db.WithSynchronous(cfg.Synchronous),
+		db.WithBusyTimeout(cfg.BusyTimeout.Std()),
+		db.WithForeignKeys(cfg.ForeignKeys),
+	}
+	if cfg.SingleWriter {
+		opts = append(opts, db.WithSingleWriter())
+	}
+	return opts
+}
+
+// routeTimeouts converts the configured per-route timeouts for the router.
+func routeTimeouts(cfg map[string]config.Duration) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(cfg))
+	for route, d := range cfg {
+		timeouts[route] = d.Std()
+	}
+	return timeouts
+}
+
+// migrateMode maps the database.migrate setting onto the startup behaviour.
+func migrateMode(setting string) db.MigrateMode {
+	if setting == "check" {
+		return db.MigrateCheck
+	}
+	return db.MigrateAuto
+}
+
+// runMigrate runs a migrate command. "down" rolls back the latest migration.
+// "up" applies pending migrations and, like "status", prints every migration
+// and fails if any is still pending.
+func runMigrate(cmd string, cfg config.Config, logger *slog.Logger) error {
+	opts := []db.Option{
+		db.WithLogger(db.NewLogger(logger, gormLogLevel(cfg.Log.Level))),
+		db.WithMigrateMode(db.MigrateSkip),
+	}
+	gormDB, err := db.InitDB(cfg.Database.Path, append(opts, connectionOptions(cfg.Database)...)...)
+	if err != nil {
+		return err
+	}
+	defer db.Close(gormDB)
+
+	switch cmd {
+	case "up":
+		if err := db.Migrate(gormDB); err != nil {
+			return err
+		}
+	case "down":
+		reverted, err := db.MigrateDown(gormDB)
+		if err != nil {
+			return err
+		}
+		logger.Info("rolled back migration", "version", reverted.Version, "name", reverted.Name)
+		return nil
+	}
+
+	statuses, err := db.MigrationStatuses(context.Background(), gormDB)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return db.CheckSchema(context.Background(), gormDB)
+}
+
+// gormLogLevel maps the service log level onto GORM's. SQL statements are
+// only logged at debug.
+func gormLogLevel(level string) gormlogger.LogLevel {
+	switch level {
+	case "debug":
+		return gormlogger.Info
+	case "error":
+		return gormlogger.Error
+	default:
+		return gormlogger.Warn
+	}
 }
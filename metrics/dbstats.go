@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"sort"
+)
+
+// dbStatsCollector reports connection pool statistics by pool, reading them
+// once per scrape.
+type dbStatsCollector struct {
+	pools []string
+	stats map[string]func() sql.DBStats
+}
+
+var dbStatsDescs = []struct {
+	desc
+	value func(sql.DBStats) float64
+}{
+	{desc{name: "db_max_open_connections", help: "Maximum number of open connections to the database.", kind: "gauge"},
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{desc{name: "db_open_connections", help: "Number of established connections, in use and idle.", kind: "gauge"},
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{desc{name: "db_in_use_connections", help: "Number of connections currently in use.", kind: "gauge"},
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{desc{name: "db_idle_connections", help: "Number of idle connections.", kind: "gauge"},
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{desc{name: "db_wait_count_total", help: "Total number of connections waited for.", kind: "counter"},
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{desc{name: "db_wait_duration_seconds_total", help: "Total time blocked waiting for a new connection.", kind: "counter"},
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{desc{name: "db_max_idle_closed_total", help: "Total number of connections closed due to the idle connection limit.", kind: "counter"},
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{desc{name: "db_max_idle_time_closed_total", help: "Total number of connections closed due to the idle time limit.", kind: "counter"},
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{desc{name: "db_max_lifetime_closed_total", help: "Total number of connections closed due to the lifetime limit.", kind: "counter"},
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+// RegisterDBStats registers the db_* connection pool metrics with a pool
+// label, read from each named pool's stats at scrape time. Pass
+// (*sql.DB).Stats for each pool.
+func (r *Registry) RegisterDBStats(pools map[string]func() sql.DBStats) {
+	names := make([]string, len(dbStatsDescs))
+	for i, d := range dbStatsDescs {
+		names[i] = d.name
+	}
+	c := dbStatsCollector{stats: pools}
+	for pool := range pools {
+		c.pools = append(c.pools, pool)
+	}
+	sort.Strings(c.pools)
+	r.register(c, names...)
+}
+
+var dbStatsLabels = []string{"pool"}
+
+func (c dbStatsCollector) write(w *bufio.Writer) {
+	stats := make([]sql.DBStats, len(c.pools))
+	for i, pool := range c.pools {
+		stats[i] = c.stats[pool]()
+	}
+	for _, d := range dbStatsDescs {
+		d.writeHeader(w)
+		for i, pool := range c.pools {
+			writeSample(w, d.name, dbStatsLabels, []string{pool}, [2]string{}, d.value(stats[i]))
+		}
+	}
+}
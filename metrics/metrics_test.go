@@ -0,0 +1,137 @@
+package metrics_test
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-service/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	var out bytes.Buffer
+	_, err := reg.WriteTo(&out)
+	assert.NoError(t, err)
+	return out.String()
+}
+
+func TestRegistry_Exposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(reg *metrics.Registry)
+		want   string
+	}{
+		{
+			name: "counter",
+			record: func(reg *metrics.Registry) {
+				c := reg.NewCounterVec("requests_total", "Requests handled.", "route", "status")
+				c.Inc("/users/:id", "200")
+				c.Add(2, "/users/:id", "200")
+				c.Inc("/users", "201")
+			},
+			want: `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{route="/users",status="201"} 1
+requests_total{route="/users/:id",status="200"} 3
+`,
+		},
+		{
+			name: "histogram",
+			record: func(reg *metrics.Registry) {
+				h := reg.NewHistogramVec("batch_size", "IDs per batch.", []float64{1, 10, 100})
+				h.Observe(1)
+				h.Observe(5)
+				h.Observe(500)
+			},
+			want: `# HELP batch_size IDs per batch.
+# TYPE batch_size histogram
+batch_size_bucket{le="1"} 1
+batch_size_bucket{le="10"} 2
+batch_size_bucket{le="100"} 2
+batch_size_bucket{le="+Inf"} 3
+batch_size_sum 506
+batch_size_count 3
+`,
+		},
+		{
+			name: "escaping",
+			record: func(reg *metrics.Registry) {
+				c := reg.NewCounterVec("odd_total", "Help with a \\ and a\nnewline.", "value")
+				c.Inc("quote \" backslash \\ newline \n")
+			},
+			want: `# HELP odd_total Help with a \\ and a\nnewline.
+# TYPE odd_total counter
+odd_total{value="quote \" backslash \\ newline \n"} 1
+`,
+		},
+		{
+			name: "gauge func",
+			record: func(reg *metrics.Registry) {
+				reg.NewGaugeFunc("queue_length", "Queued items.", func() float64 { return 7 })
+			},
+			want: `# HELP queue_length Queued items.
+# TYPE queue_length gauge
+queue_length 7
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := metrics.NewRegistry()
+			tt.record(reg)
+			assert.Equal(t, tt.want, scrape(t, reg))
+		})
+	}
+}
+
+func TestRegistry_DBStats(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.RegisterDBStats(map[string]func() sql.DBStats{
+		"read": func() sql.DBStats {
+			return sql.DBStats{MaxOpenConnections: 4, OpenConnections: 3, InUse: 2, Idle: 1, WaitDuration: 1500 * time.Millisecond}
+		},
+		"write": func() sql.DBStats {
+			return sql.DBStats{MaxOpenConnections: 1, OpenConnections: 1, InUse: 1}
+		},
+	})
+
+	out := scrape(t, reg)
+	for _, line := range []string{
+		`db_max_open_connections{pool="read"} 4`,
+		`db_max_open_connections{pool="write"} 1`,
+		`db_open_connections{pool="read"} 3`,
+		`db_in_use_connections{pool="read"} 2`,
+		`db_idle_connections{pool="read"} 1`,
+		`db_idle_connections{pool="write"} 0`,
+		"# TYPE db_wait_duration_seconds_total counter",
+		`db_wait_duration_seconds_total{pool="read"} 1.5`,
+	} {
+		assert.Contains(t, strings.Split(out, "\n"), line)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewCounterVec("hits_total", "Hits.").Inc()
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "hits_total 1\n")
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := reg.NewCounterVec("dup_total", "Duplicate.", "a")
+
+	assert.Panics(t, func() { reg.NewCounterVec("dup_total", "Again.") })
+	assert.Panics(t, func() { c.Inc() })
+	assert.Panics(t, func() { c.Add(-1, "x") })
+}
@@ -50,11 +50,40 @@ func (mr *MockUserServiceMockRecorder) CreateUser(ctx, name interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), ctx, name)
 }
 
+// CreateUsers mocks base method.
+func (m *MockUserService) CreateUsers(ctx context.Context, names []string, mode model.BulkMode) ([]model.BulkCreateResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUsers", ctx, names, mode)
+	ret0, _ := ret[0].([]model.BulkCreateResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUsers indicates an expected call of CreateUsers.
+func (mr *MockUserServiceMockRecorder) CreateUsers(ctx, names, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsers", reflect.TypeOf((*MockUserService)(nil).CreateUsers), ctx, names, mode)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserService) DeleteUser(ctx context.Context, id uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserServiceMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserService)(nil).DeleteUser), ctx, id)
+}
+
 // GetAllUsers mocks base method.
-func (m *MockUserService) GetAllUsers(ctx context.Context, page, size int) ([]model.User, error) {
+func (m *MockUserService) GetAllUsers(ctx context.Context, page, size int) (model.UserPage, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAllUsers", ctx, page, size)
-	ret0, _ := ret[0].([]model.User)
+	ret0, _ := ret[0].(model.UserPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -81,10 +110,10 @@ func (mr *MockUserServiceMockRecorder) GetUser(ctx, id interface{}) *gomock.Call
 }
 
 // GetUsersByIDs mocks base method.
-func (m *MockUserService) GetUsersByIDs(ctx context.Context, ids []uint64) ([]model.User, error) {
+func (m *MockUserService) GetUsersByIDs(ctx context.Context, ids []uint64) (model.BatchFetchUsersResponse, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetUsersByIDs", ctx, ids)
-	ret0, _ := ret[0].([]model.User)
+	ret0, _ := ret[0].(model.BatchFetchUsersResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -94,3 +123,78 @@ func (mr *MockUserServiceMockRecorder) GetUsersByIDs(ctx, ids interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIDs", reflect.TypeOf((*MockUserService)(nil).GetUsersByIDs), ctx, ids)
 }
+
+// ListUsersByCursor mocks base method.
+func (m *MockUserService) ListUsersByCursor(ctx context.Context, cursor string, size int) (model.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsersByCursor", ctx, cursor, size)
+	ret0, _ := ret[0].(model.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsersByCursor indicates an expected call of ListUsersByCursor.
+func (mr *MockUserServiceMockRecorder) ListUsersByCursor(ctx, cursor, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsersByCursor", reflect.TypeOf((*MockUserService)(nil).ListUsersByCursor), ctx, cursor, size)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockUserServiceMockRecorder) PurgeDeletedUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockUserService)(nil).PurgeDeletedUsers), ctx)
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserService) RestoreUser(ctx context.Context, id uint64) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", ctx, id)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserServiceMockRecorder) RestoreUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserService)(nil).RestoreUser), ctx, id)
+}
+
+// SearchUsers mocks base method.
+func (m *MockUserService) SearchUsers(ctx context.Context, query string, mode model.SearchMode, page, size int) (model.UserSearchPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, query, mode, page, size)
+	ret0, _ := ret[0].(model.UserSearchPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockUserServiceMockRecorder) SearchUsers(ctx, query, mode, page, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockUserService)(nil).SearchUsers), ctx, query, mode, page, size)
+}
+
+// UpdateUser mocks base method.
+func (m *MockUserService) UpdateUser(ctx context.Context, id uint64, patch model.UserPatch, expectedVersion uint64) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, id, patch, expectedVersion)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockUserServiceMockRecorder) UpdateUser(ctx, id, patch, expectedVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserService)(nil).UpdateUser), ctx, id, patch, expectedVersion)
+}
@@ -5,12 +5,111 @@ type CreateUserRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
-// BatchFetchUsersRequest is the request payload for batch fetching users
+// BulkCreateUsersRequest is the request payload for creating many users at once
+type BulkCreateUsersRequest struct {
+	Users []CreateUserRequest `json:"users" binding:"required"`
+}
+
+// BulkMode controls how a bulk create treats invalid or failing items.
+type BulkMode string
+
+const (
+	// BulkModeAtomic creates every item in one transaction or none at all.
+	BulkModeAtomic BulkMode = "atomic"
+	// BulkModePartial creates the valid items and reports the rest individually.
+	BulkModePartial BulkMode = "partial"
+)
+
+// BulkItemStatus is the outcome of a single bulk create item.
+type BulkItemStatus string
+
+const (
+	BulkItemCreated BulkItemStatus = "created"
+	BulkItemFailed  BulkItemStatus = "failed"
+	BulkItemSkipped BulkItemStatus = "skipped" // not attempted because an atomic batch was aborted
+)
+
+// BulkCreateResult reports the outcome for one item of a bulk create, by its
+// position in the request
+type BulkCreateResult struct {
+	Index  int            `json:"index"`
+	Status BulkItemStatus `json:"status"`
+	User   *User          `json:"user,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// BatchFetchUsersRequest is the request payload for batch fetching users.
+// Shape "map" returns users keyed by ID instead of as a list.
 type BatchFetchUsersRequest struct {
 	UserIDs []uint64 `json:"user_ids" binding:"required"`
+	Shape   string   `json:"shape" binding:"omitempty,oneof=list map"`
 }
 
-// BatchFetchUsersResponse is the response containing user data
+// BatchFetchUsersResponse is the response containing user data. Users follow
+// the order of the requested IDs without duplicates, and IDs with no matching
+// user are listed in NotFound.
 type BatchFetchUsersResponse struct {
-	Users []User `json:"users"`
+	Users    []User   `json:"users"`
+	NotFound []uint64 `json:"not_found"`
+}
+
+// PatchFormat identifies the media type of a user patch document.
+type PatchFormat int
+
+const (
+	// MergePatch is a JSON Merge Patch document (RFC 7396).
+	MergePatch PatchFormat = iota
+	// JSONPatch is a JSON Patch operation list (RFC 6902).
+	JSONPatch
+)
+
+// UserPatch is a raw patch document for UpdateUser parameters
+type UserPatch struct {
+	Format PatchFormat
+	Body   []byte
+}
+
+// UserCursor is a position in the users listing, which is ordered by
+// (created_at, id) descending.
+type UserCursor struct {
+	CreatedAt int64  `json:"c"`
+	ID        uint64 `json:"i"`
+	Before    bool   `json:"b,omitempty"` // page towards newer users instead of older ones
+}
+
+// UserPage is a page of users plus paging metadata and opaque cursors for
+// the adjacent pages.
+type UserPage struct {
+	Users      []User
+	Total      int64 // Number of users across all pages
+	Page       int   // 1-based page number; 0 for cursor-based pages
+	PageSize   int
+	HasNext    bool
+	NextCursor string
+	PrevCursor string
+}
+
+// SearchMode selects how a name search query is matched.
+type SearchMode string
+
+const (
+	// SearchModePrefix matches names containing every query word as a word prefix.
+	SearchModePrefix SearchMode = "prefix"
+	// SearchModeFuzzy matches names by trigram similarity, tolerating typos and diacritics.
+	SearchModeFuzzy SearchMode = "fuzzy"
+)
+
+// UserMatch is a search hit with its relevance score; higher is better.
+type UserMatch struct {
+	User  User    `json:"user" gorm:"embedded"`
+	Score float64 `json:"score"`
+}
+
+// UserSearchPage is a page of search hits plus paging metadata.
+type UserSearchPage struct {
+	Matches  []UserMatch
+	Total    int64
+	Page     int
+	PageSize int
+	HasNext  bool
 }
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+	"user-service/metrics"
+	"user-service/model"
+)
+
+// Defaults for NewBatchingRepo.
+const (
+	DefaultMaxBatchSize = 64
+	DefaultMaxBatchWait = 2 * time.Millisecond
+)
+
+// BatchOption customizes a batching UserRepository.
+type BatchOption func(*batchingRepo)
+
+// WithMaxBatchSize flushes a batch as soon as it holds n users.
+func WithMaxBatchSize(n int) BatchOption {
+	return func(r *batchingRepo) {
+		r.maxSize = n
+	}
+}
+
+// WithMaxBatchWait flushes a batch once its first user has waited for d.
+func WithMaxBatchWait(d time.Duration) BatchOption {
+	return func(r *batchingRepo) {
+		r.maxWait = d
+	}
+}
+
+// WithBatchMetrics records the size of every flushed batch in
+// user_create_batch_size.
+func WithBatchMetrics(reg *metrics.Registry) BatchOption {
+	return func(r *batchingRepo) {
+		r.sizes = reg.NewHistogramVec("user_create_batch_size",
+			"Users inserted per group-committed CreateUser batch.", metrics.ExponentialBuckets(1, 2, 8))
+	}
+}
+
+// batchingRepo coalesces concurrent CreateUser calls into CreateUsers calls.
+// Every other method goes straight to the wrapped repository.
+type batchingRepo struct {
+	UserRepository
+	maxSize int
+	maxWait time.Duration
+	sizes   *metrics.HistogramVec
+
+	mu      sync.Mutex
+	pending *createBatch
+}
+
+// createBatch is a group of CreateUser calls inserted together.
+type createBatch struct {
+	calls []createCall
+	timer *time.Timer
+}
+
+type createCall struct {
+	ctx    context.Context
+	name   string
+	result chan createResult
+}
+
+type createResult struct {
+	user model.User
+	err  error
+}
+
+// NewBatchingRepo wraps inner so that CreateUser calls arriving within a
+// short window are inserted in one transaction, and so one disk sync,
+// instead of one each. A batch is flushed when it reaches the maximum size
+// or its first call has waited the maximum wait, whichever comes first. If
+// the batch insert fails, its users are created one at a time so that each
+// caller gets its own result.
+func NewBatchingRepo(inner UserRepository, opts ...BatchOption) UserRepository {
+	r := &batchingRepo{UserRepository: inner, maxSize: DefaultMaxBatchSize, maxWait: DefaultMaxBatchWait}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// CreateUser queues the user for the next batch and waits for it to be
+// written. If ctx ends first, CreateUser returns its error; the user is then
+// created only if the batch was already being written.
+func (r *batchingRepo) CreateUser(ctx context.Context, name string) (model.User, error) {
+	call := createCall{ctx: ctx, name: name, result: make(chan createResult, 1)}
+
+	r.mu.Lock()
+	b := r.pending
+	if b == nil {
+		b = &createBatch{}
+		b.timer = time.AfterFunc(r.maxWait, func() { r.flush(b) })
+		r.pending = b
+	}
+	b.calls = append(b.calls, call)
+	full := len(b.calls) >= r.maxSize
+	r.mu.Unlock()
+
+	if full {
+		r.flush(b)
+	}
+
+	select {
+	case res := <-call.result:
+		return res.user, res.err
+	case <-ctx.Done():
+		return model.User{}, translateError(ctx.Err())
+	}
+}
+
+// flush writes b, unless the timer and a full batch both tried and the
+// other got there first.
+func (r *batchingRepo) flush(b *createBatch) {
+	r.mu.Lock()
+	if r.pending != b {
+		r.mu.Unlock()
+		return
+	}
+	r.pending = nil
+	b.timer.Stop()
+	r.mu.Unlock()
+
+	// Skip callers that gave up while waiting.
+	calls := make([]createCall, 0, len(b.calls))
+	for _, c := range b.calls {
+		if err := c.ctx.Err(); err != nil {
+			c.result <- createResult{err: translateError(err)}
+			continue
+		}
+		calls = append(calls, c)
+	}
+	if len(calls) == 0 {
+		return
+	}
+	if r.sizes != nil {
+		r.sizes.Observe(float64(len(calls)))
+	}
+
+	// The batch runs under the first caller's request ID and span, and must
+	// finish before the tightest deadline in it. A caller cancelling does
+	// not cancel the batch for the others.
+	ctx := context.WithoutCancel(calls[0].ctx)
+	var deadline time.Time
+	for _, c := range calls {
+		if d, ok := c.ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	names := make([]string, len(calls))
+	for i, c := range calls {
+		names[i] = c.name
+	}
+	users, err := r.UserRepository.CreateUsers(ctx, names)
+	if err == nil {
+		for i, c := range calls {
+			c.result <- createResult{user: users[i]}
+		}
+		return
+	}
+
+	if len(calls) == 1 {
+		calls[0].result <- createResult{err: err}
+		return
+	}
+	// Retried singly, each user is created on its own caller's context.
+	for _, c := range calls {
+		user, err := r.UserRepository.CreateUser(c.ctx, c.name)
+		c.result <- createResult{user: user, err: err}
+	}
+}
@@ -0,0 +1,193 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"user-service/logging"
+	"user-service/metrics"
+	"user-service/model"
+	"user-service/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// batchRecorder records the names of every CreateUsers and CreateUser call
+// it passes on.
+type batchRecorder struct {
+	repository.UserRepository
+	mu      sync.Mutex
+	batches [][]string
+	singles []string
+	ctxs    []context.Context // of every call, in order
+}
+
+func (r *batchRecorder) CreateUsers(ctx context.Context, names []string) ([]model.User, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, names)
+	r.ctxs = append(r.ctxs, ctx)
+	r.mu.Unlock()
+	return r.UserRepository.CreateUsers(ctx, names)
+}
+
+func (r *batchRecorder) CreateUser(ctx context.Context, name string) (model.User, error) {
+	r.mu.Lock()
+	r.singles = append(r.singles, name)
+	r.ctxs = append(r.ctxs, ctx)
+	r.mu.Unlock()
+	return r.UserRepository.CreateUser(ctx, name)
+}
+
+func newBatchRecorder(t *testing.T) *batchRecorder {
+	gormDB := setupTestDB(t)
+	// Every connection to ":memory:" is a separate database.
+	sqlDB, err := gormDB.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return &batchRecorder{UserRepository: repository.NewUserRepo(gormDB)}
+}
+
+// createConcurrently calls CreateUser for every name at once and returns the
+// results in the order of names.
+func createConcurrently(ctx context.Context, repo repository.UserRepository, names []string) ([]model.User, []error) {
+	users := make([]model.User, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users[i], errs[i] = repo.CreateUser(ctx, name)
+		}()
+	}
+	wg.Wait()
+	return users, errs
+}
+
+func TestBatchingRepo_CoalescesCreates(t *testing.T) {
+	ctx := context.Background()
+	inner := newBatchRecorder(t)
+	reg := metrics.NewRegistry()
+	// Only full batches are flushed within the test.
+	repo := repository.NewBatchingRepo(inner, repository.WithMaxBatchSize(10),
+		repository.WithMaxBatchWait(time.Hour), repository.WithBatchMetrics(reg))
+
+	names := make([]string, 30)
+	for i := range names {
+		names[i] = fmt.Sprintf("User %d", i)
+	}
+	users, errs := createConcurrently(ctx, repo, names)
+
+	seen := make(map[uint64]bool)
+	for i, user := range users {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, names[i], user.Name)
+		assert.False(t, seen[user.ID], "ID %d handed out twice", user.ID)
+		seen[user.ID] = true
+
+		got, err := repo.GetUser(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, names[i], got.Name)
+	}
+	assert.Len(t, inner.batches, 3)
+	for _, batch := range inner.batches {
+		assert.Len(t, batch, 10)
+	}
+	assert.Empty(t, inner.singles)
+
+	var out bytes.Buffer
+	_, err := reg.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "user_create_batch_size_count 3\n")
+	assert.Contains(t, out.String(), "user_create_batch_size_sum 30\n")
+}
+
+func TestBatchingRepo_MaxWait(t *testing.T) {
+	inner := newBatchRecorder(t)
+	repo := repository.NewBatchingRepo(inner, repository.WithMaxBatchSize(100),
+		repository.WithMaxBatchWait(20*time.Millisecond))
+
+	start := time.Now()
+	user, err := repo.CreateUser(context.Background(), "Alone")
+	assert.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, [][]string{{"Alone"}}, inner.batches)
+}
+
+func TestBatchingRepo_FailedBatchIsRetriedSingly(t *testing.T) {
+	inner := newBatchRecorder(t)
+	repo := repository.NewBatchingRepo(inner, repository.WithMaxBatchSize(3),
+		repository.WithMaxBatchWait(time.Hour))
+
+	users, errs := createConcurrently(context.Background(), repo, []string{"Ann", "", "Bob"})
+
+	assert.NoError(t, errs[0])
+	assert.Equal(t, "Ann", users[0].Name)
+	assert.ErrorIs(t, errs[1], repository.ErrValidation)
+	assert.NoError(t, errs[2])
+	assert.Equal(t, "Bob", users[2].Name)
+	assert.Len(t, inner.batches, 1)
+	assert.ElementsMatch(t, []string{"Ann", "", "Bob"}, inner.singles)
+}
+
+func TestBatchingRepo_RunsOnCallerContexts(t *testing.T) {
+	inner := newBatchRecorder(t)
+	repo := repository.NewBatchingRepo(inner, repository.WithMaxBatchSize(2),
+		repository.WithMaxBatchWait(time.Hour))
+
+	first, cancel := context.WithTimeout(logging.WithRequestID(context.Background(), "req-1"), time.Hour)
+	defer cancel()
+	sooner := time.Now().Add(time.Minute)
+	second, cancel := context.WithDeadline(logging.WithRequestID(context.Background(), "req-2"), sooner)
+	defer cancel()
+
+	// The empty name fails the batch, so both users are retried singly.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := repo.CreateUser(first, "")
+		assert.ErrorIs(t, err, repository.ErrValidation)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_, err := repo.CreateUser(second, "Bob")
+	assert.NoError(t, err)
+	wg.Wait()
+
+	if assert.Len(t, inner.ctxs, 3) {
+		// The batch runs under the first caller's request ID and the
+		// tightest deadline.
+		assert.Equal(t, "req-1", logging.RequestID(inner.ctxs[0]))
+		deadline, ok := inner.ctxs[0].Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, sooner, deadline, time.Millisecond)
+
+		// Single retries run on their own caller's context.
+		assert.Equal(t, "req-1", logging.RequestID(inner.ctxs[1]))
+		assert.Equal(t, "req-2", logging.RequestID(inner.ctxs[2]))
+	}
+}
+
+func TestBatchingRepo_CancelledCallIsDropped(t *testing.T) {
+	ctx := context.Background()
+	inner := newBatchRecorder(t)
+	repo := repository.NewBatchingRepo(inner, repository.WithMaxBatchSize(2),
+		repository.WithMaxBatchWait(time.Hour))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := repo.CreateUser(cancelled, "Gone")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Fills the batch, which then holds only the call still waiting.
+	_, err = repo.CreateUser(ctx, "Kept")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Kept"}}, inner.batches)
+	count, err := repo.CountUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"user-service/metrics"
+	"user-service/model"
+)
+
+// Defaults for NewCachingRepo.
+const (
+	DefaultCacheSize        = 10000
+	DefaultCacheTTL         = 30 * time.Second
+	DefaultNegativeCacheTTL = 5 * time.Second
+)
+
+// CacheOption customizes a caching UserRepository.
+type CacheOption func(*cachingRepo)
+
+// WithCacheSize sets how many IDs the cache holds before evicting the least
+// recently used.
+func WithCacheSize(n int) CacheOption {
+	return func(r *cachingRepo) {
+		r.size = n
+	}
+}
+
+// WithCacheTTL sets how long a cached user is served before it is read again.
+func WithCacheTTL(d time.Duration) CacheOption {
+	return func(r *cachingRepo) {
+		r.ttl = d
+	}
+}
+
+// WithNegativeCacheTTL sets how long an ID with no user is remembered as
+// missing.
+func WithNegativeCacheTTL(d time.Duration) CacheOption {
+	return func(r *cachingRepo) {
+		r.negativeTTL = d
+	}
+}
+
+// WithCacheMetrics counts cache lookups by result, "hit" or "miss", in
+// user_cache_lookups_total and reports the number of cached IDs in
+// user_cache_entries.
+func WithCacheMetrics(reg *metrics.Registry) CacheOption {
+	return func(r *cachingRepo) {
+		r.lookups = reg.NewCounterVec("user_cache_lookups_total",
+			"User cache lookups by result.", "result")
+		reg.NewGaugeFunc("user_cache_entries", "IDs held in the user cache.", func() float64 {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return float64(r.lru.Len())
+		})
+	}
+}
+
+// cachingRepo serves GetUser and GetUserByIDs from an in-process LRU cache
+// of users that are not soft-deleted. Every other read goes straight to the
+// wrapped repository.
+type cachingRepo struct {
+	UserRepository
+	size             int
+	ttl, negativeTTL time.Duration
+	lookups          *metrics.CounterVec
+
+	mu      sync.Mutex
+	entries map[uint64]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+	// writes counts the writes made through the cache, so that a read which
+	// overlapped one does not cache what it read.
+	writes uint64
+}
+
+// cacheEntry is a cached user, or the absence of one if found is false.
+type cacheEntry struct {
+	id      uint64
+	user    model.User
+	found   bool
+	expires time.Time
+}
+
+// NewCachingRepo wraps inner with a read-through cache for lookups by ID.
+// IDs that have no user are cached too, for a shorter time. Writes through
+// the cache update or drop the entries they affect; writes made elsewhere,
+// such as by another process, show up once the entries expire. Reads under
+// WithDeleted bypass the cache.
+func NewCachingRepo(inner UserRepository, opts ...CacheOption) UserRepository {
+	r := &cachingRepo{
+		UserRepository: inner,
+		size:           DefaultCacheSize,
+		ttl:            DefaultCacheTTL,
+		negativeTTL:    DefaultNegativeCacheTTL,
+		entries:        make(map[uint64]*list.Element),
+		lru:            list.New(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *cachingRepo) GetUser(ctx context.Context, id uint64) (model.User, error) {
+	if IncludesDeleted(ctx) {
+		return r.UserRepository.GetUser(ctx, id)
+	}
+	if e, ok := r.lookup(id); ok {
+		if !e.found {
+			return model.User{}, ErrUserNotFound
+		}
+		return e.user, nil
+	}
+
+	writes := r.writeCount()
+	user, err := r.UserRepository.GetUser(ctx, id)
+	switch {
+	case err == nil:
+		r.fill(writes, cacheEntry{id: id, user: user, found: true})
+	case errors.Is(err, ErrUserNotFound):
+		r.fill(writes, cacheEntry{id: id})
+	}
+	return user, err
+}
+
+// GetUserByIDs answers what it can from the cache and fetches only the
+// remaining IDs.
+func (r *cachingRepo) GetUserByIDs(ctx context.Context, ids []uint64) ([]model.User, error) {
+	if IncludesDeleted(ctx) {
+		return r.UserRepository.GetUserByIDs(ctx, ids)
+	}
+
+	users := make([]model.User, 0, len(ids))
+	var misses []uint64
+	seen := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		e, ok := r.lookup(id)
+		switch {
+		case !ok:
+			misses = append(misses, id)
+		case e.found:
+			users = append(users, e.user)
+		}
+	}
+	if len(misses) == 0 {
+		return users, nil
+	}
+
+	writes := r.writeCount()
+	fetched, err := r.UserRepository.GetUserByIDs(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint64]struct{}, len(fetched))
+	for _, user := range fetched {
+		found[user.ID] = struct{}{}
+		r.fill(writes, cacheEntry{id: user.ID, user: user, found: true})
+	}
+	for _, id := range misses {
+		if _, ok := found[id]; !ok {
+			r.fill(writes, cacheEntry{id: id})
+		}
+	}
+	return append(users, fetched...), nil
+}
+
+func (r *cachingRepo) CreateUser(ctx context.Context, name string) (model.User, error) {
+	user, err := r.UserRepository.CreateUser(ctx, name)
+	if err == nil {
+		// The ID may have been cached as missing before it was assigned.
+		r.store(user)
+	}
+	return user, err
+}
+
+func (r *cachingRepo) CreateUsers(ctx context.Context, names []string) ([]model.User, error) {
+	users, err := r.UserRepository.CreateUsers(ctx, names)
+	if err == nil {
+		r.store(users...)
+	}
+	return users, err
+}
+
+func (r *cachingRepo) UpdateUser(ctx context.Context, user model.User, expectedVersion uint64) (model.User, error) {
+	updated, err := r.UserRepository.UpdateUser(ctx, user, expectedVersion)
+	if err != nil {
+		// A conflict means the cached copy may be stale.
+		r.invalidate(user.ID)
+		return updated, err
+	}
+	r.store(updated)
+	return updated, nil
+}
+
+func (r *cachingRepo) DeleteUser(ctx context.Context, id uint64) error {
+	err := r.UserRepository.DeleteUser(ctx, id)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachingRepo) RestoreUser(ctx context.Context, id uint64, deletedSince int64) (model.User, error) {
+	user, err := r.UserRepository.RestoreUser(ctx, id, deletedSince)
+	if err != nil {
+		r.invalidate(id)
+		return user, err
+	}
+	r.store(user)
+	return user, nil
+}
+
+// lookup returns the live entry for id, marking it most recently used, and
+// counts the lookup as a hit or a miss.
+func (r *cachingRepo) lookup(id uint64) (cacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[id]
+	if ok && time.Now().After(el.Value.(*cacheEntry).expires) {
+		r.remove(el)
+		ok = false
+	}
+	if r.lookups != nil {
+		if ok {
+			r.lookups.Inc("hit")
+		} else {
+			r.lookups.Inc("miss")
+		}
+	}
+	if !ok {
+		return cacheEntry{}, false
+	}
+	r.lru.MoveToFront(el)
+	return *el.Value.(*cacheEntry), true
+}
+
+// writeCount returns the number of writes so far, to pass to fill.
+func (r *cachingRepo) writeCount() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writes
+}
+
+// fill caches e, read from the database when writeCount returned writes,
+// unless a write has been made through the cache since.
+func (r *cachingRepo) fill(writes uint64, e cacheEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.writes == writes {
+		r.put(e)
+	}
+}
+
+// store caches users just written.
+func (r *cachingRepo) store(users ...model.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes++
+	for _, user := range users {
+		r.put(cacheEntry{id: user.ID, user: user, found: true})
+	}
+}
+
+// invalidate drops ids from the cache after a write that may have changed
+// them.
+func (r *cachingRepo) invalidate(ids ...uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes++
+	for _, id := range ids {
+		if el, ok := r.entries[id]; ok {
+			r.remove(el)
+		}
+	}
+}
+
+// put adds or replaces e, evicting the least recently used entry if the
+// cache is full. r.mu must be held.
+func (r *cachingRepo) put(e cacheEntry) {
+	ttl := r.ttl
+	if !e.found {
+		ttl = r.negativeTTL
+	}
+	e.expires = time.Now().Add(ttl)
+
+	if el, ok := r.entries[e.id]; ok {
+		*el.Value.(*cacheEntry) = e
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.entries[e.id] = r.lru.PushFront(&e)
+	if r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+	}
+}
+
+// remove drops el from the cache. r.mu must be held.
+func (r *cachingRepo) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.entries, el.Value.(*cacheEntry).id)
+}
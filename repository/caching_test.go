@@ -0,0 +1,219 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"user-service/metrics"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingRepo_GetUser(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	reg := metrics.NewRegistry()
+	repo := repository.NewCachingRepo(inner, repository.WithCacheMetrics(reg),
+		repository.WithNegativeCacheTTL(20*time.Millisecond))
+
+	ann := model.User{ID: 1, Name: "Ann", Version: 1}
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(ann, nil).Times(1)
+	inner.EXPECT().GetUser(ctx, uint64(2)).Return(model.User{}, repository.ErrUserNotFound).Times(2)
+	inner.EXPECT().GetUser(ctx, uint64(3)).Return(model.User{}, repository.ErrUnavailable).Times(2)
+
+	for range 3 {
+		user, err := repo.GetUser(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, ann, user)
+
+		_, err = repo.GetUser(ctx, 2)
+		assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	}
+	// Failures other than a missing user are not cached.
+	for range 2 {
+		_, err := repo.GetUser(ctx, 3)
+		assert.ErrorIs(t, err, repository.ErrUnavailable)
+	}
+	// Missing users are remembered for the negative TTL only.
+	time.Sleep(30 * time.Millisecond)
+	_, err := repo.GetUser(ctx, 2)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	var out bytes.Buffer
+	_, err = reg.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `user_cache_lookups_total{result="hit"} 4`+"\n")
+	assert.Contains(t, out.String(), `user_cache_lookups_total{result="miss"} 5`+"\n")
+	assert.Contains(t, out.String(), "user_cache_entries 2\n")
+}
+
+func TestCachingRepo_GetUserByIDs(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewCachingRepo(inner)
+
+	ann := model.User{ID: 1, Name: "Ann"}
+	bob := model.User{ID: 2, Name: "Bob"}
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(ann, nil)
+	inner.EXPECT().GetUser(ctx, uint64(3)).Return(model.User{}, repository.ErrUserNotFound)
+	_, _ = repo.GetUser(ctx, 1)
+	_, _ = repo.GetUser(ctx, 3)
+
+	// Only the IDs the cache knows nothing about are fetched.
+	inner.EXPECT().GetUserByIDs(ctx, []uint64{2, 4}).Return([]model.User{bob}, nil)
+	users, err := repo.GetUserByIDs(ctx, []uint64{1, 2, 3, 4, 1})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []model.User{ann, bob}, users)
+
+	// Now every ID is cached, as a user or as missing.
+	users, err = repo.GetUserByIDs(ctx, []uint64{4, 3, 2, 1})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []model.User{ann, bob}, users)
+
+	inner.EXPECT().GetUserByIDs(ctx, []uint64{5}).Return(nil, repository.ErrUnavailable)
+	_, err = repo.GetUserByIDs(ctx, []uint64{1, 5})
+	assert.ErrorIs(t, err, repository.ErrUnavailable)
+}
+
+func TestCachingRepo_WritesUpdateCache(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewCachingRepo(inner)
+
+	// A created user replaces the cached absence of its ID.
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(model.User{}, repository.ErrUserNotFound)
+	_, err := repo.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	ann := model.User{ID: 1, Name: "Ann", Version: 1}
+	inner.EXPECT().CreateUser(ctx, "Ann").Return(ann, nil)
+	_, err = repo.CreateUser(ctx, "Ann")
+	assert.NoError(t, err)
+	user, err := repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, ann, user)
+
+	// Bulk-created users are cached as well.
+	bob := model.User{ID: 2, Name: "Bob", Version: 1}
+	inner.EXPECT().CreateUsers(ctx, []string{"Bob"}).Return([]model.User{bob}, nil)
+	_, err = repo.CreateUsers(ctx, []string{"Bob"})
+	assert.NoError(t, err)
+	users, err := repo.GetUserByIDs(ctx, []uint64{2})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.User{bob}, users)
+
+	// An update caches the new version.
+	renamed := model.User{ID: 1, Name: "Anna", Version: 2}
+	inner.EXPECT().UpdateUser(ctx, model.User{ID: 1, Name: "Anna", Version: 1}, uint64(1)).Return(renamed, nil)
+	_, err = repo.UpdateUser(ctx, model.User{ID: 1, Name: "Anna", Version: 1}, 1)
+	assert.NoError(t, err)
+	user, err = repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, renamed, user)
+
+	// A failed update drops the entry, which may be stale.
+	inner.EXPECT().UpdateUser(ctx, gomock.Any(), uint64(2)).Return(model.User{}, repository.ErrVersionConflict)
+	_, err = repo.UpdateUser(ctx, renamed, 2)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(renamed, nil)
+	_, err = repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+
+	// A delete drops the entry.
+	inner.EXPECT().DeleteUser(ctx, uint64(1)).Return(nil)
+	assert.NoError(t, repo.DeleteUser(ctx, 1))
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(model.User{}, repository.ErrUserNotFound)
+	_, err = repo.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	// A restore caches the restored user.
+	restored := model.User{ID: 1, Name: "Anna", Version: 4}
+	inner.EXPECT().RestoreUser(ctx, uint64(1), int64(0)).Return(restored, nil)
+	_, err = repo.RestoreUser(ctx, 1, 0)
+	assert.NoError(t, err)
+	user, err = repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, restored, user)
+}
+
+func TestCachingRepo_WithDeletedBypassesCache(t *testing.T) {
+	ctx := repository.WithDeleted(context.Background())
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewCachingRepo(inner)
+
+	deletedAt := int64(1)
+	gone := model.User{ID: 1, Name: "Gone", DeletedAt: &deletedAt}
+	inner.EXPECT().GetUser(ctx, uint64(1)).Return(gone, nil).Times(2)
+	inner.EXPECT().GetUserByIDs(ctx, []uint64{1}).Return([]model.User{gone}, nil).Times(2)
+	for range 2 {
+		user, err := repo.GetUser(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, gone, user)
+		users, err := repo.GetUserByIDs(ctx, []uint64{1})
+		assert.NoError(t, err)
+		assert.Equal(t, []model.User{gone}, users)
+	}
+
+	// Nothing read under WithDeleted is served to other readers.
+	inner.EXPECT().GetUser(context.Background(), uint64(1)).Return(model.User{}, repository.ErrUserNotFound)
+	_, err := repo.GetUser(context.Background(), 1)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+}
+
+func TestCachingRepo_Eviction(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewCachingRepo(inner, repository.WithCacheSize(2),
+		repository.WithCacheTTL(20*time.Millisecond))
+
+	fetches := make(map[uint64]int)
+	inner.EXPECT().GetUser(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, id uint64) (model.User, error) {
+		fetches[id]++
+		return model.User{ID: id}, nil
+	}).AnyTimes()
+
+	for _, id := range []uint64{1, 2, 1, 3, 1, 2} {
+		_, err := repo.GetUser(ctx, id)
+		assert.NoError(t, err)
+	}
+	// 3 evicted 2, the least recently used, and 2 then evicted 3.
+	assert.Equal(t, map[uint64]int{1: 1, 2: 2, 3: 1}, fetches)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches[1], "expired entries are read again")
+}
+
+func TestCachingRepo_WriteDuringReadIsNotOverwritten(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewCachingRepo(inner)
+
+	stale := model.User{ID: 1, Name: "Old", Version: 1}
+	fresh := model.User{ID: 1, Name: "New", Version: 2}
+	// The update lands while the read is in flight, so the read must not
+	// cache the version it saw.
+	inner.EXPECT().GetUser(ctx, uint64(1)).DoAndReturn(func(context.Context, uint64) (model.User, error) {
+		inner.EXPECT().UpdateUser(ctx, gomock.Any(), uint64(1)).Return(fresh, nil)
+		_, err := repo.UpdateUser(ctx, fresh, 1)
+		return stale, err
+	})
+	user, err := repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, stale, user)
+
+	user, err = repo.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, fresh, user)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sqlite "github.com/glebarez/go-sqlite"
+	"gorm.io/gorm"
+)
+
+// Error kinds. Every error returned by a UserRepository matches at most one
+// of them under errors.Is, so callers can react to the kind of failure
+// without knowing about GORM or the SQLite driver.
+var (
+	// ErrNotFound means the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write clashes with the current state of the record.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the database rejected the data as invalid.
+	ErrValidation = errors.New("invalid data")
+	// ErrUnavailable means the database could not serve the request right now.
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrTimeout means the request ran out of time before the database answered.
+	ErrTimeout = errors.New("database timeout")
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the requested ID.
+	ErrUserNotFound error = kindError{"user not found", ErrNotFound}
+	// ErrVersionConflict is returned when a write is based on a stale user version.
+	ErrVersionConflict error = kindError{"user version conflict", ErrConflict}
+	// ErrUserNotDeleted is returned when restoring a user that is not soft-deleted.
+	ErrUserNotDeleted error = kindError{"user is not deleted", ErrConflict}
+	// ErrRetentionExpired is returned when a soft-deleted user is past its restore window.
+	ErrRetentionExpired error = kindError{"user retention window expired", ErrNotFound}
+)
+
+// kindError is a specific sentinel error that also matches its broader kind.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e kindError) Error() string { return e.msg }
+
+func (e kindError) Unwrap() error { return e.kind }
+
+// SQLite primary result codes the repository tells apart.
+const (
+	sqliteBusy       = 5
+	sqliteLocked     = 6
+	sqliteReadOnly   = 8
+	sqliteIOErr      = 10
+	sqliteFull       = 13
+	sqliteCantOpen   = 14
+	sqliteConstraint = 19
+
+	sqliteConstraintCheck   = 275
+	sqliteConstraintNotNull = 1299
+)
+
+// translateError wraps err so it matches the error kind it belongs to,
+// keeping the original error in the chain. Errors that already carry a kind
+// and errors of no known kind are returned unchanged.
+func translateError(err error) error {
+	var kind error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrConflict), errors.Is(err, ErrValidation),
+		errors.Is(err, ErrUnavailable), errors.Is(err, ErrTimeout):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		kind = ErrUserNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrTimeout
+	case errors.Is(err, sql.ErrConnDone), errors.Is(err, gorm.ErrInvalidDB):
+		kind = ErrUnavailable
+	default:
+		var sqliteErr *sqlite.Error
+		if !errors.As(err, &sqliteErr) {
+			return err
+		}
+		switch code := sqliteErr.Code(); {
+		case code == sqliteConstraintCheck, code == sqliteConstraintNotNull:
+			kind = ErrValidation
+		case code&0xff == sqliteConstraint:
+			kind = ErrConflict
+		case code&0xff == sqliteBusy, code&0xff == sqliteLocked, code&0xff == sqliteReadOnly,
+			code&0xff == sqliteIOErr, code&0xff == sqliteFull, code&0xff == sqliteCantOpen:
+			kind = ErrUnavailable
+		default:
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
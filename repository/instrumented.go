@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/model"
+)
+
+// instrumentedRepo records the latency and errors of every call to another
+// UserRepository.
+type instrumentedRepo struct {
+	inner    UserRepository
+	duration *metrics.HistogramVec
+	errors   *metrics.CounterVec
+}
+
+// NewInstrumentedRepo wraps inner so that every method call is timed in
+// user_repository_duration_seconds and every failure is counted by kind in
+// user_repository_errors_total.
+func NewInstrumentedRepo(inner UserRepository, reg *metrics.Registry) UserRepository {
+	return &instrumentedRepo{
+		inner: inner,
+		duration: reg.NewHistogramVec("user_repository_duration_seconds",
+			"Latency of UserRepository calls.", metrics.DefBuckets, "method"),
+		errors: reg.NewCounterVec("user_repository_errors_total",
+			"UserRepository calls that returned an error, by error kind.", "method", "kind"),
+	}
+}
+
+// observe records a call to method that started at start and ended with err.
+func (r *instrumentedRepo) observe(method string, start time.Time, err error) {
+	r.duration.Observe(time.Since(start).Seconds(), method)
+	if err != nil {
+		r.errors.Inc(method, errorKind(err))
+	}
+}
+
+// errorKinds label errors in metrics, checked in order.
+var errorKinds = []struct {
+	err   error
+	label string
+}{
+	{ErrNotFound, "not_found"},
+	{ErrConflict, "conflict"},
+	{ErrValidation, "validation"},
+	{ErrUnavailable, "unavailable"},
+	{ErrTimeout, "timeout"},
+}
+
+// errorKind returns the metrics label for the kind of err.
+func errorKind(err error) string {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.label
+		}
+	}
+	return "internal"
+}
+
+func (r *instrumentedRepo) CreateUser(ctx context.Context, name string) (model.User, error) {
+	start := time.Now()
+	user, err := r.inner.CreateUser(ctx, name)
+	r.observe("CreateUser", start, err)
+	return user, err
+}
+
+func (r *instrumentedRepo) CreateUsers(ctx context.Context, names []string) ([]model.User, error) {
+	start := time.Now()
+	users, err := r.inner.CreateUsers(ctx, names)
+	r.observe("CreateUsers", start, err)
+	return users, err
+}
+
+func (r *instrumentedRepo) GetUser(ctx context.Context, id uint64) (model.User, error) {
+	start := time.Now()
+	user, err := r.inner.GetUser(ctx, id)
+	r.observe("GetUser", start, err)
+	return user, err
+}
+
+func (r *instrumentedRepo) GetUserByIDs(ctx context.Context, ids []uint64) ([]model.User, error) {
+	start := time.Now()
+	users, err := r.inner.GetUserByIDs(ctx, ids)
+	r.observe("GetUserByIDs", start, err)
+	return users, err
+}
+
+func (r *instrumentedRepo) GetAllUsers(ctx context.Context, offset, limit int) ([]model.User, error) {
+	start := time.Now()
+	users, err := r.inner.GetAllUsers(ctx, offset, limit)
+	r.observe("GetAllUsers", start, err)
+	return users, err
+}
+
+func (r *instrumentedRepo) GetUsersByCursor(ctx context.Context, cursor model.UserCursor, limit int) ([]model.User, error) {
+	start := time.Now()
+	users, err := r.inner.GetUsersByCursor(ctx, cursor, limit)
+	r.observe("GetUsersByCursor", start, err)
+	return users, err
+}
+
+func (r *instrumentedRepo) CountUsers(ctx context.Context) (int64, error) {
+	start := time.Now()
+	n, err := r.inner.CountUsers(ctx)
+	r.observe("CountUsers", start, err)
+	return n, err
+}
+
+func (r *instrumentedRepo) SearchUsers(ctx context.Context, query string, offset, limit int) ([]model.UserMatch, int64, error) {
+	start := time.Now()
+	matches, total, err := r.inner.SearchUsers(ctx, query, offset, limit)
+	r.observe("SearchUsers", start, err)
+	return matches, total, err
+}
+
+func (r *instrumentedRepo) FuzzySearchUsers(ctx context.Context, query string, minScore float64, offset, limit int) ([]model.UserMatch, int64, error) {
+	start := time.Now()
+	matches, total, err := r.inner.FuzzySearchUsers(ctx, query, minScore, offset, limit)
+	r.observe("FuzzySearchUsers", start, err)
+	return matches, total, err
+}
+
+func (r *instrumentedRepo) UpdateUser(ctx context.Context, user model.User, expectedVersion uint64) (model.User, error) {
+	start := time.Now()
+	updated, err := r.inner.UpdateUser(ctx, user, expectedVersion)
+	r.observe("UpdateUser", start, err)
+	return updated, err
+}
+
+func (r *instrumentedRepo) DeleteUser(ctx context.Context, id uint64) error {
+	start := time.Now()
+	err := r.inner.DeleteUser(ctx, id)
+	r.observe("DeleteUser", start, err)
+	return err
+}
+
+func (r *instrumentedRepo) RestoreUser(ctx context.Context, id uint64, deletedSince int64) (model.User, error) {
+	start := time.Now()
+	user, err := r.inner.RestoreUser(ctx, id, deletedSince)
+	r.observe("RestoreUser", start, err)
+	return user, err
+}
+
+func (r *instrumentedRepo) PurgeUsers(ctx context.Context, deletedBefore int64) (int64, error) {
+	start := time.Now()
+	n, err := r.inner.PurgeUsers(ctx, deletedBefore)
+	r.observe("PurgeUsers", start, err)
+	return n, err
+}
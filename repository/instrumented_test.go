@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"user-service/metrics"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedRepo(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	inner := mocks.NewMockUserRepository(ctrl)
+	reg := metrics.NewRegistry()
+	repo := repository.NewInstrumentedRepo(inner, reg)
+
+	inner.EXPECT().CreateUser(ctx, "Ann").Return(model.User{ID: 1, Name: "Ann"}, nil)
+	inner.EXPECT().GetUser(ctx, uint64(2)).Return(model.User{}, repository.ErrUserNotFound)
+	inner.EXPECT().DeleteUser(ctx, uint64(3)).Return(repository.ErrUnavailable)
+	inner.EXPECT().CountUsers(ctx).Return(int64(0), errors.New("boom"))
+
+	user, err := repo.CreateUser(ctx, "Ann")
+	assert.NoError(t, err)
+	assert.Equal(t, "Ann", user.Name)
+
+	_, err = repo.GetUser(ctx, 2)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	assert.ErrorIs(t, repo.DeleteUser(ctx, 3), repository.ErrUnavailable)
+	_, err = repo.CountUsers(ctx)
+	assert.Error(t, err)
+
+	var out bytes.Buffer
+	_, err = reg.WriteTo(&out)
+	assert.NoError(t, err)
+	for _, line := range []string{
+		`user_repository_duration_seconds_count{method="CreateUser"} 1`,
+		`user_repository_duration_seconds_count{method="GetUser"} 1`,
+		`user_repository_errors_total{method="CountUsers",kind="internal"} 1`,
+		`user_repository_errors_total{method="DeleteUser",kind="unavailable"} 1`,
+		`user_repository_errors_total{method="GetUser",kind="not_found"} 1`,
+	} {
+		assert.Contains(t, out.String(), line+"\n")
+	}
+	assert.NotContains(t, out.String(), `errors_total{method="CreateUser"`)
+}
@@ -0,0 +1,160 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"user-service/db"
+	"user-service/model"
+	"user-service/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// queryPlans records the EXPLAIN QUERY PLAN output of every statement run
+// through a database, keyed by SQL.
+type queryPlans struct {
+	mu    sync.Mutex
+	plans map[string][]string
+}
+
+// recordQueryPlans explains each query, update and delete gormDB runs from
+// now on. Inserts are not explained; they never scan.
+func recordQueryPlans(t *testing.T, gormDB *gorm.DB) *queryPlans {
+	p := &queryPlans{plans: make(map[string][]string)}
+	cb := gormDB.Callback()
+	for _, err := range []error{
+		cb.Query().After("gorm:query").Register("test:explain_query", p.explain),
+		cb.Update().After("gorm:update").Register("test:explain_update", p.explain),
+		cb.Delete().After("gorm:delete").Register("test:explain_delete", p.explain),
+		cb.Row().After("gorm:row").Register("test:explain_row", p.explain),
+		cb.Raw().After("gorm:raw").Register("test:explain_raw", p.explain),
+	} {
+		if err != nil {
+			t.Fatalf("register explain callback: %v", err)
+		}
+	}
+	return p
+}
+
+func (p *queryPlans) explain(tx *gorm.DB) {
+	sql := tx.Statement.SQL.String()
+	if tx.Error != nil || sql == "" || strings.HasPrefix(sql, "INSERT") {
+		return
+	}
+	// The statement's own connection, so explaining inside a transaction
+	// does not wait for it, and no callbacks run for the EXPLAIN itself.
+	rows, err := tx.Statement.ConnPool.QueryContext(context.Background(), "EXPLAIN QUERY PLAN "+sql, tx.Statement.Vars...)
+	if err != nil {
+		tx.AddError(err)
+		return
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			tx.AddError(err)
+			return
+		}
+		plan = append(plan, detail)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.plans[sql] = plan
+}
+
+// fullScan matches a plan step that reads a whole table without an index.
+// Scans of the full-text index are searches despite the wording.
+var fullScan = regexp.MustCompile(`^SCAN (\w+)$`)
+
+// subquery matches a plan step computing a subquery, whose rows are then
+// scanned by name; such a scan is not a table scan.
+var subquery = regexp.MustCompile(`^(?:CO-ROUTINE|MATERIALIZE) (\w+)$`)
+
+// sortedWithoutIndex matches a plan step that sorts rows because no index
+// delivers them in order.
+var sortedWithoutIndex = regexp.MustCompile(`USE TEMP B-TREE FOR (ORDER|GROUP) BY`)
+
+// rankedQueries sort by a computed score, which no index can provide.
+var rankedQueries = []string{"bm25(", "scored.score"}
+
+func TestUserRepo_QueryPlans(t *testing.T) {
+	ctx := context.Background()
+	// A file, so explaining on a second connection sees the same schema.
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "plans.db"), db.WithLogger(logger.Discard))
+	assert.NoError(t, err)
+	plans := recordQueryPlans(t, gormDB)
+	repo := repository.NewUserRepo(gormDB)
+
+	// Run every repository method that reads, updates or deletes.
+	users, err := repo.CreateUsers(ctx, []string{"Alice Smith", "Bob Stone", "Carol Smythe"})
+	assert.NoError(t, err)
+	_, err = repo.GetUser(ctx, users[0].ID)
+	assert.NoError(t, err)
+	_, err = repo.GetUserByIDs(ctx, []uint64{users[0].ID, users[1].ID})
+	assert.NoError(t, err)
+	for _, listCtx := range []context.Context{ctx, repository.WithDeleted(ctx)} {
+		_, err = repo.GetAllUsers(listCtx, 0, 2)
+		assert.NoError(t, err)
+		_, err = repo.CountUsers(listCtx)
+		assert.NoError(t, err)
+		cursor := model.UserCursor{CreatedAt: users[1].CreatedAt, ID: users[1].ID}
+		_, err = repo.GetUsersByCursor(listCtx, cursor, 2)
+		assert.NoError(t, err)
+		cursor.Before = true
+		_, err = repo.GetUsersByCursor(listCtx, cursor, 2)
+		assert.NoError(t, err)
+		_, _, err = repo.SearchUsers(listCtx, "smith", 0, 10)
+		assert.NoError(t, err)
+		_, _, err = repo.FuzzySearchUsers(listCtx, "smth", 0.1, 0, 10)
+		assert.NoError(t, err)
+	}
+	users[2].Name = "Carol Smith"
+	_, err = repo.UpdateUser(ctx, users[2], users[2].Version)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.DeleteUser(ctx, users[1].ID))
+	_, err = repo.RestoreUser(ctx, users[1].ID, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.DeleteUser(ctx, users[1].ID))
+	_, err = repo.PurgeUsers(ctx, time.Now().Add(time.Hour).UnixMicro())
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, plans.plans)
+	statements := make([]string, 0, len(plans.plans))
+	for sql := range plans.plans {
+		statements = append(statements, sql)
+	}
+	sort.Strings(statements)
+	for _, sql := range statements {
+		plan := plans.plans[sql]
+		t.Logf("%s\n\t%s", sql, strings.Join(plan, "\n\t"))
+		ranked := false
+		for _, marker := range rankedQueries {
+			ranked = ranked || strings.Contains(sql, marker)
+		}
+		subqueries := make(map[string]bool)
+		for _, step := range plan {
+			if m := subquery.FindStringSubmatch(step); m != nil {
+				subqueries[m[1]] = true
+			}
+		}
+		for _, step := range plan {
+			m := fullScan.FindStringSubmatch(step)
+			assert.False(t, m != nil && !subqueries[m[1]], "full table scan:\n%s\n%s", sql, strings.Join(plan, "\n"))
+			if !ranked {
+				assert.False(t, sortedWithoutIndex.MatchString(step), "sort without index:\n%s\n%s", sql, strings.Join(plan, "\n"))
+			}
+		}
+	}
+}
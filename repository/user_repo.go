@@ -2,7 +2,13 @@ package repository
 
 import (
 	"context"
+	"math"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"user-service/db"
+	"user-service/fuzzy"
 	"user-service/model"
 
 	"gorm.io/gorm"
@@ -13,19 +19,79 @@ import (
 //go:generate mockgen -source=user_repo.go -destination=../mocks/mock_user_repo.go -package=mocks
 type UserRepository interface {
 	CreateUser(ctx context.Context, name string) (model.User, error)
+	CreateUsers(ctx context.Context, names []string) ([]model.User, error)
 	GetUser(ctx context.Context, id uint64) (model.User, error)
 	GetUserByIDs(ctx context.Context, ids []uint64) ([]model.User, error)
 	GetAllUsers(ctx context.Context, offset, limit int) ([]model.User, error)
+	GetUsersByCursor(ctx context.Context, cursor model.UserCursor, limit int) ([]model.User, error)
+	CountUsers(ctx context.Context) (int64, error)
+	SearchUsers(ctx context.Context, query string, offset, limit int) ([]model.UserMatch, int64, error)
+	FuzzySearchUsers(ctx context.Context, query string, minScore float64, offset, limit int) ([]model.UserMatch, int64, error)
+	UpdateUser(ctx context.Context, user model.User, expectedVersion uint64) (model.User, error)
+	DeleteUser(ctx context.Context, id uint64) error
+	RestoreUser(ctx context.Context, id uint64, deletedSince int64) (model.User, error)
+	PurgeUsers(ctx context.Context, deletedBefore int64) (int64, error)
+}
+
+// includeDeletedKey marks a context whose reads should also return soft-deleted users.
+type includeDeletedKey struct{}
+
+// WithDeleted returns a context under which repository reads include soft-deleted users.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IncludesDeleted reports whether ctx was created by WithDeleted.
+func IncludesDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return v
+}
+
+// DefaultIDChunkSize is how many IDs GetUserByIDs binds per query, safely
+// below SQLite's bound parameter limit.
+const DefaultIDChunkSize = 500
+
+// Option customizes a UserRepository.
+type Option func(*userRepoImpl)
+
+// WithIDChunkSize sets how many IDs GetUserByIDs binds per query.
+func WithIDChunkSize(size int) Option {
+	return func(r *userRepoImpl) {
+		r.idChunkSize = size
+	}
+}
+
+// WithParallelReads lets GetUserByIDs run up to n chunk queries concurrently.
+func WithParallelReads(n int) Option {
+	return func(r *userRepoImpl) {
+		r.parallelReads = n
+	}
 }
 
 // userRepoImpl is the concrete implementation of UserRepository using GORM.
 type userRepoImpl struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	idChunkSize   int
+	parallelReads int
 }
 
 // NewUserRepo initializes the db and returns a UserRepository.
-func NewUserRepo(db *gorm.DB) UserRepository {
-	return &userRepoImpl{DB: db}
+func NewUserRepo(db *gorm.DB, opts ...Option) UserRepository {
+	r := &userRepoImpl{DB: db, idChunkSize: DefaultIDChunkSize, parallelReads: 1}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// scoped returns a query bound to ctx that hides soft-deleted users unless
+// the context opted in via WithDeleted.
+func (r *userRepoImpl) scoped(ctx context.Context) *gorm.DB {
+	db := r.DB.WithContext(ctx)
+	if IncludesDeleted(ctx) {
+		return db
+	}
+	return db.Where("deleted_at IS NULL")
 }
 
 func (r *userRepoImpl) CreateUser(ctx context.Context, name string) (model.User, error) {
@@ -34,26 +100,357 @@ func (r *userRepoImpl) CreateUser(ctx context.Context, name string) (model.User,
 		Name:      name,
 		CreatedAt: now,
 		UpdatedAt: now,
+		Version:   1,
+	}
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return db.IndexTrigrams(tx, user)
+	})
+	return user, translateError(err)
+}
+
+// createBatchSize keeps multi-row user inserts under SQLite's bound parameter limit.
+const createBatchSize = 200
+
+// CreateUsers inserts all names in a single transaction using multi-row
+// inserts. Either every user is created or none is.
+func (r *userRepoImpl) CreateUsers(ctx context.Context, names []string) ([]model.User, error) {
+	now := time.Now().UnixMicro()
+	users := make([]model.User, len(names))
+	for i, name := range names {
+		users[i] = model.User{Name: name, CreatedAt: now, UpdatedAt: now, Version: 1}
+	}
+	if len(users) == 0 {
+		return users, nil
+	}
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&users, createBatchSize).Error; err != nil {
+			return err
+		}
+		return db.IndexTrigrams(tx, users...)
+	})
+	if err != nil {
+		return nil, translateError(err)
 	}
-	result := r.DB.WithContext(ctx).Create(&user)
-	return user, result.Error
+	return users, nil
 }
 
 func (r *userRepoImpl) GetUser(ctx context.Context, id uint64) (model.User, error) {
 	var user model.User
-	result := r.DB.WithContext(ctx).First(&user, id)
-	return user, result.Error
+	result := r.scoped(ctx).First(&user, id)
+	return user, translateError(result.Error)
 }
 
+// GetUserByIDs splits large ID lists into chunks of idChunkSize so a single
+// query never exceeds SQLite's bound parameter limit, optionally running the
+// chunks in parallel, and merges the results.
 func (r *userRepoImpl) GetUserByIDs(ctx context.Context, ids []uint64) ([]model.User, error) {
+	if len(ids) <= r.idChunkSize {
+		return r.getUserByIDChunk(ctx, ids)
+	}
+
+	var chunks [][]uint64
+	for start := 0; start < len(ids); start += r.idChunkSize {
+		chunks = append(chunks, ids[start:min(start+r.idChunkSize, len(ids))])
+	}
+
+	results := make([][]model.User, len(chunks))
+	if r.parallelReads <= 1 {
+		for i, chunk := range chunks {
+			users, err := r.getUserByIDChunk(ctx, chunk)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = users
+		}
+	} else if err := r.getUserByIDChunksParallel(ctx, chunks, results); err != nil {
+		return nil, translateError(err)
+	}
+
+	user := make([]model.User, 0, len(ids))
+	for _, users := range results {
+		user = append(user, users...)
+	}
+	return user, nil
+}
+
+// getUserByIDChunksParallel fills results[i] with the users of chunks[i],
+// running at most parallelReads queries at once and stopping at the first error.
+func (r *userRepoImpl) getUserByIDChunksParallel(ctx context.Context, chunks [][]uint64, results [][]model.User) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, r.parallelReads)
+	)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+			users, err := r.getUserByIDChunk(ctx, chunk)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = users
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		// The caller's context ended before every chunk ran.
+		return context.Cause(ctx)
+	}
+	return firstErr
+}
+
+func (r *userRepoImpl) getUserByIDChunk(ctx context.Context, ids []uint64) ([]model.User, error) {
 	user := make([]model.User, 0)
 
-	result := r.DB.WithContext(ctx).Where("id in (?)", ids).Find(&user)
-	return user, result.Error
+	result := r.scoped(ctx).Where("id in (?)", ids).Find(&user)
+	return user, translateError(result.Error)
 }
 
 func (r *userRepoImpl) GetAllUsers(ctx context.Context, offset, limit int) ([]model.User, error) {
 	var users []model.User
-	result := r.DB.WithContext(ctx).Order("created_at desc").Offset(offset).Limit(limit).Find(&users)
-	return users, result.Error
+	result := r.scoped(ctx).Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&users)
+	return users, translateError(result.Error)
+}
+
+// GetUsersByCursor returns up to limit users adjacent to cursor using keyset
+// pagination. Results are always ordered by (created_at, id) descending; when
+// cursor.Before is set they are the users immediately newer than the cursor.
+func (r *userRepoImpl) GetUsersByCursor(ctx context.Context, cursor model.UserCursor, limit int) ([]model.User, error) {
+	var users []model.User
+
+	query := r.scoped(ctx)
+	if cursor.Before {
+		query = query.
+			Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID).
+			Order("created_at asc, id asc")
+	} else {
+		query = query.
+			Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID).
+			Order("created_at desc, id desc")
+	}
+
+	result := query.Limit(limit).Find(&users)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	if cursor.Before {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	return users, nil
+}
+
+func (r *userRepoImpl) CountUsers(ctx context.Context) (int64, error) {
+	var total int64
+	result := r.scoped(ctx).Model(&model.User{}).Count(&total)
+	return total, translateError(result.Error)
+}
+
+// UpdateUser writes the mutable fields of user only if the stored row is still
+// at expectedVersion, bumping the version and UpdatedAt on success.
+func (r *userRepoImpl) UpdateUser(ctx context.Context, user model.User, expectedVersion uint64) (model.User, error) {
+	now := time.Now().UnixMicro()
+	var updated bool
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&model.User{}).
+			Where("id = ? AND version = ? AND deleted_at IS NULL", user.ID, expectedVersion).
+			Updates(map[string]interface{}{
+				"name":       user.Name,
+				"updated_at": now,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		updated = true
+		return db.IndexTrigrams(tx, user)
+	})
+	if err != nil {
+		return model.User{}, translateError(err)
+	}
+
+	if !updated {
+		// Either the row is gone or someone else updated it first.
+		if _, err := r.GetUser(ctx, user.ID); err != nil {
+			return model.User{}, err
+		}
+		return model.User{}, ErrVersionConflict
+	}
+
+	return r.GetUser(ctx, user.ID)
+}
+
+// DeleteUser soft-deletes a user by stamping deleted_at.
+func (r *userRepoImpl) DeleteUser(ctx context.Context, id uint64) error {
+	now := time.Now().UnixMicro()
+	result := r.DB.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": now,
+			"updated_at": now,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RestoreUser clears deleted_at for a user that was soft-deleted at or after deletedSince.
+func (r *userRepoImpl) RestoreUser(ctx context.Context, id uint64, deletedSince int64) (model.User, error) {
+	now := time.Now().UnixMicro()
+	result := r.DB.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND deleted_at >= ?", id, deletedSince).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"updated_at": now,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return model.User{}, translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		user, err := r.GetUser(WithDeleted(ctx), id)
+		switch {
+		case err != nil:
+			return model.User{}, err
+		case user.DeletedAt == nil:
+			return model.User{}, ErrUserNotDeleted
+		default:
+			return model.User{}, ErrRetentionExpired
+		}
+	}
+
+	return r.GetUser(ctx, id)
+}
+
+// PurgeUsers permanently removes users soft-deleted before deletedBefore and
+// returns how many rows were removed.
+func (r *userRepoImpl) PurgeUsers(ctx context.Context, deletedBefore int64) (int64, error) {
+	result := r.DB.WithContext(ctx).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&model.User{})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// SearchUsers runs a prefix full-text search over user names, ranked by BM25,
+// and returns one page of hits along with the total number of hits.
+func (r *userRepoImpl) SearchUsers(ctx context.Context, query string, offset, limit int) ([]model.UserMatch, int64, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return []model.UserMatch{}, 0, nil
+	}
+
+	filter := "users_fts MATCH ?"
+	if !IncludesDeleted(ctx) {
+		filter += " AND users.deleted_at IS NULL"
+	}
+
+	var total int64
+	result := db.Read(r.DB.WithContext(ctx)).
+		Raw("SELECT COUNT(*) FROM users_fts JOIN users ON users.id = users_fts.rowid WHERE "+filter, match).
+		Scan(&total)
+	if result.Error != nil {
+		return nil, 0, translateError(result.Error)
+	}
+
+	matches := make([]model.UserMatch, 0)
+	result = db.Read(r.DB.WithContext(ctx)).
+		Raw("SELECT users.*, -bm25(users_fts) AS score FROM users_fts JOIN users ON users.id = users_fts.rowid WHERE "+filter+
+			" ORDER BY bm25(users_fts), users.id LIMIT ? OFFSET ?", match, limit, offset).
+		Scan(&matches)
+	return matches, total, translateError(result.Error)
+}
+
+// ftsQuery turns free text into an FTS5 expression that prefix-matches every
+// word. Words are quoted so FTS5 operators in user input are treated literally.
+func ftsQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// fuzzyScores selects every user sharing at least a given number of trigrams
+// with the query, scored like fuzzy.SetSimilarity: the shared trigrams over
+// the trigrams in either name. Its parameters are the query trigrams, the
+// minimum shared count and the number of query trigrams.
+const fuzzyScores = `WITH shared AS (
+	SELECT user_id, COUNT(*) AS n FROM user_name_trigrams WHERE trigram IN ? GROUP BY user_id HAVING COUNT(*) >= ?
+), scored AS (
+	SELECT shared.user_id, CAST(shared.n AS REAL) /
+		(? + (SELECT COUNT(*) FROM user_name_trigrams t WHERE t.user_id = shared.user_id) - shared.n) AS score
+	FROM shared
+) `
+
+// FuzzySearchUsers finds users whose names have a trigram similarity of at
+// least minScore with query, best matches first, and returns one page of hits
+// along with the total number of hits.
+func (r *userRepoImpl) FuzzySearchUsers(ctx context.Context, query string, minScore float64, offset, limit int) ([]model.UserMatch, int64, error) {
+	grams := fuzzy.Trigrams(query)
+	if len(grams) == 0 {
+		return []model.UserMatch{}, 0, nil
+	}
+
+	// A name can only reach minScore if it shares at least minScore*|grams|
+	// trigrams with the query, which lets SQLite discard most rows early.
+	minShared := int(math.Ceil(minScore * float64(len(grams))))
+	if minShared < 1 {
+		minShared = 1
+	}
+
+	from := "FROM scored JOIN users ON users.id = scored.user_id WHERE scored.score >= ?"
+	if !IncludesDeleted(ctx) {
+		from += " AND users.deleted_at IS NULL"
+	}
+	args := []any{grams, minShared, len(grams), minScore}
+
+	var total int64
+	result := db.Read(r.DB.WithContext(ctx)).
+		Raw(fuzzyScores+"SELECT COUNT(*) "+from, args...).
+		Scan(&total)
+	if result.Error != nil {
+		return nil, 0, translateError(result.Error)
+	}
+
+	matches := make([]model.UserMatch, 0)
+	result = db.Read(r.DB.WithContext(ctx)).
+		Raw(fuzzyScores+"SELECT users.*, scored.score "+from+" ORDER BY scored.score DESC, users.id LIMIT ? OFFSET ?",
+			append(args, limit, offset)...).
+		Scan(&matches)
+	return matches, total, translateError(result.Error)
 }
@@ -2,25 +2,31 @@ package repository_test
 
 import (
 	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"user-service/db"
 	"user-service/model"
 	"user-service/repository"
 
 	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("failed to connect to database: %v", err)
 	}
-	err = db.AutoMigrate(&model.User{})
+	err = db.Migrate(gormDB)
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
-	return db
+	return gormDB
 }
 
 func TestUserRepo_CreateUser(t *testing.T) {
@@ -34,14 +40,17 @@ func TestUserRepo_CreateUser(t *testing.T) {
 		wantErr bool
 	}{
 		{"valid user", "John Doe", false},
-		{"empty name", "", false}, // no validation on name
+		{"longest name", strings.Repeat("é", 255), false},
+		{"empty name", "", true},
+		{"blank name", "   ", true},
+		{"name too long", strings.Repeat("a", 256), true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			user, err := repo.CreateUser(ctx, tt.input)
 			if tt.wantErr {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, repository.ErrValidation)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.input, user.Name)
@@ -169,3 +178,516 @@ func TestUserRepo_GetAllUsers(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepo_UpdateUser(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	created, _ := repo.CreateUser(ctx, "Alice")
+
+	tests := []struct {
+		name            string
+		user            model.User
+		expectedVersion uint64
+		wantErr         error
+		wantVersion     uint64
+	}{
+		{"current version", model.User{ID: created.ID, Name: "Alicia"}, 1, nil, 2},
+		{"stale version", model.User{ID: created.ID, Name: "Ally"}, 1, repository.ErrVersionConflict, 0},
+		{"user not found", model.User{ID: 9999, Name: "Nobody"}, 1, repository.ErrUserNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := repo.UpdateUser(ctx, tt.user, tt.expectedVersion)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.user.Name, user.Name)
+			assert.Equal(t, tt.wantVersion, user.Version)
+			assert.Equal(t, created.CreatedAt, user.CreatedAt)
+			assert.GreaterOrEqual(t, user.UpdatedAt, created.UpdatedAt)
+		})
+	}
+}
+
+func TestUserRepo_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	alice, _ := repo.CreateUser(ctx, "Alice")
+	bob, _ := repo.CreateUser(ctx, "Bob")
+
+	assert.NoError(t, repo.DeleteUser(ctx, alice.ID))
+	assert.ErrorIs(t, repo.DeleteUser(ctx, alice.ID), repository.ErrUserNotFound)
+	assert.ErrorIs(t, repo.DeleteUser(ctx, 9999), repository.ErrUserNotFound)
+
+	_, err := repo.GetUser(ctx, alice.ID)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	users, err := repo.GetUserByIDs(ctx, []uint64{alice.ID, bob.ID})
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	users, err = repo.GetAllUsers(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	deleted, err := repo.GetUser(repository.WithDeleted(ctx), alice.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	users, err = repo.GetAllUsers(repository.WithDeleted(ctx), 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestUserRepo_RestoreUser(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	alice, _ := repo.CreateUser(ctx, "Alice")
+	bob, _ := repo.CreateUser(ctx, "Bob")
+	carol, _ := repo.CreateUser(ctx, "Carol")
+	_ = repo.DeleteUser(ctx, alice.ID)
+	_ = repo.DeleteUser(ctx, carol.ID)
+
+	tests := []struct {
+		name         string
+		id           uint64
+		deletedSince int64
+		wantErr      error
+	}{
+		{"within retention", alice.ID, 0, nil},
+		{"not deleted", bob.ID, 0, repository.ErrUserNotDeleted},
+		{"retention expired", carol.ID, time.Now().Add(time.Hour).UnixMicro(), repository.ErrRetentionExpired},
+		{"user not found", 9999, 0, repository.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := repo.RestoreUser(ctx, tt.id, tt.deletedSince)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Nil(t, user.DeletedAt)
+			assert.Equal(t, uint64(3), user.Version)
+		})
+	}
+}
+
+func TestUserRepo_PurgeUsers(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	alice, _ := repo.CreateUser(ctx, "Alice")
+	bob, _ := repo.CreateUser(ctx, "Bob")
+	_ = repo.DeleteUser(ctx, alice.ID)
+
+	purged, err := repo.PurgeUsers(ctx, time.Now().Add(time.Second).UnixMicro())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = repo.GetUser(repository.WithDeleted(ctx), alice.ID)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	_, err = repo.GetUser(ctx, bob.ID)
+	assert.NoError(t, err)
+}
+
+func TestUserRepo_GetUsersByCursor(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	// Seed users A..E; E is the newest and therefore first in the listing.
+	var seeded []model.User
+	for i := 0; i < 5; i++ {
+		user, _ := repo.CreateUser(ctx, "User"+string(rune('A'+i)))
+		seeded = append(seeded, user)
+	}
+	middle := seeded[2]
+
+	tests := []struct {
+		name      string
+		cursor    model.UserCursor
+		limit     int
+		wantNames []string
+	}{
+		{"older than cursor", model.UserCursor{CreatedAt: middle.CreatedAt, ID: middle.ID}, 10, []string{"UserB", "UserA"}},
+		{"newer than cursor", model.UserCursor{CreatedAt: middle.CreatedAt, ID: middle.ID, Before: true}, 10, []string{"UserE", "UserD"}},
+		{"newer than cursor limited", model.UserCursor{CreatedAt: middle.CreatedAt, ID: middle.ID, Before: true}, 1, []string{"UserD"}},
+		{"past the end", model.UserCursor{CreatedAt: seeded[0].CreatedAt, ID: seeded[0].ID}, 10, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.GetUsersByCursor(ctx, tt.cursor, tt.limit)
+			assert.NoError(t, err)
+
+			gotNames := make([]string, len(users))
+			for i, u := range users {
+				gotNames[i] = u.Name
+			}
+			assert.Equal(t, tt.wantNames, gotNames)
+		})
+	}
+}
+
+func TestUserRepo_CountUsers(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		_, _ = repo.CreateUser(ctx, name)
+	}
+	bob, _ := repo.GetUser(ctx, 2)
+	_ = repo.DeleteUser(ctx, bob.ID)
+
+	total, err := repo.CountUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	total, err = repo.CountUsers(repository.WithDeleted(ctx))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestUserRepo_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	john, _ := repo.CreateUser(ctx, "John Doe")
+	_, _ = repo.CreateUser(ctx, "Johnny Appleseed")
+	jane, _ := repo.CreateUser(ctx, "Jane Doe")
+	renamed, _ := repo.CreateUser(ctx, "Old Name")
+	_, _ = repo.CreateUser(ctx, "Zoë Saldaña")
+	for _, name := range []string{"Alice Smith", "Bob Brown", "Carol White", "Dave Black"} {
+		_, _ = repo.CreateUser(ctx, name)
+	}
+	_, _ = repo.UpdateUser(ctx, model.User{ID: renamed.ID, Name: "The Doe Family"}, renamed.Version)
+	_ = repo.DeleteUser(ctx, jane.ID)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		query     string
+		wantNames []string
+		wantTotal int64
+	}{
+		{"prefix match", ctx, "joh", []string{"John Doe", "Johnny Appleseed"}, 2},
+		{"all words must match", ctx, "john do", []string{"John Doe"}, 1},
+		{"updated name is indexed", ctx, "family", []string{"The Doe Family"}, 1},
+		{"old name is not indexed", ctx, "old", []string{}, 0},
+		{"soft-deleted hidden", ctx, "jane", []string{}, 0},
+		{"soft-deleted included", repository.WithDeleted(ctx), "jane", []string{"Jane Doe"}, 1},
+		{"diacritics folded", ctx, "zoe", []string{"Zoë Saldaña"}, 1},
+		{"fts syntax is literal", ctx, `john" OR "jane`, []string{}, 0},
+		{"punctuation only", ctx, "***", []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, total, err := repo.SearchUsers(tt.ctx, tt.query, 0, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, total)
+
+			gotNames := make([]string, len(matches))
+			for i, m := range matches {
+				gotNames[i] = m.User.Name
+				assert.NotZero(t, m.User.ID)
+			}
+			assert.ElementsMatch(t, tt.wantNames, gotNames)
+		})
+	}
+
+	t.Run("ranked and paginated", func(t *testing.T) {
+		matches, total, err := repo.SearchUsers(ctx, "doe", 0, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, matches, 1)
+
+		// The shorter name is the denser match and ranks first.
+		assert.Equal(t, john.ID, matches[0].User.ID)
+
+		rest, _, err := repo.SearchUsers(ctx, "doe", 1, 1)
+		assert.NoError(t, err)
+		assert.Len(t, rest, 1)
+		assert.Greater(t, matches[0].Score, rest[0].Score)
+	})
+}
+
+func TestUserRepo_FuzzySearchUsers(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	john, _ := repo.CreateUser(ctx, "John Doe")
+	_, _ = repo.CreateUser(ctx, "Jane Roe")
+	renamed, _ := repo.CreateUser(ctx, "Alice Smith")
+	deleted, _ := repo.CreateUser(ctx, "John Doe")
+	_, _ = repo.UpdateUser(ctx, model.User{ID: renamed.ID, Name: "Jonathan Dow"}, renamed.Version)
+	_ = repo.DeleteUser(ctx, deleted.ID)
+
+	for _, query := range []string{"Jon Doe", "John Do", "Jöhn Doe", "john  DOE"} {
+		t.Run(query, func(t *testing.T) {
+			matches, total, err := repo.FuzzySearchUsers(ctx, query, 0.3, 0, 10)
+			assert.NoError(t, err)
+			assert.NotZero(t, total)
+			assert.Equal(t, john.ID, matches[0].User.ID)
+			assert.Greater(t, matches[0].Score, 0.3)
+			for i := 1; i < len(matches); i++ {
+				assert.GreaterOrEqual(t, matches[i-1].Score, matches[i].Score)
+				assert.NotEqual(t, deleted.ID, matches[i].User.ID)
+			}
+		})
+	}
+
+	t.Run("renamed user matches new name only", func(t *testing.T) {
+		matches, _, err := repo.FuzzySearchUsers(ctx, "Alice Smith", 0.3, 0, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, matches)
+
+		matches, _, err = repo.FuzzySearchUsers(ctx, "Jonathon Dow", 0.3, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, renamed.ID, matches[0].User.ID)
+	})
+
+	t.Run("threshold filters weak matches", func(t *testing.T) {
+		matches, total, err := repo.FuzzySearchUsers(ctx, "John Doe", 1, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, john.ID, matches[0].User.ID)
+		assert.Equal(t, 1.0, matches[0].Score)
+	})
+
+	t.Run("include deleted", func(t *testing.T) {
+		_, total, err := repo.FuzzySearchUsers(repository.WithDeleted(ctx), "John Doe", 1, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		all, total, _ := repo.FuzzySearchUsers(ctx, "Jon Doe", 0.1, 0, 10)
+		page, pageTotal, err := repo.FuzzySearchUsers(ctx, "Jon Doe", 0.1, 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, total, pageTotal)
+		assert.Equal(t, all[1:2], page)
+
+		page, _, err = repo.FuzzySearchUsers(ctx, "Jon Doe", 0.1, 50, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, page)
+	})
+}
+
+func TestUserRepo_FuzzySearchUsers_ManyMatches(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	// More matches than the old limit of 1000 scored candidates, with the
+	// best match created last.
+	names := make([]string, 1500)
+	for i := range names {
+		names[i] = "John Doe " + strconv.Itoa(i)
+	}
+	_, err := repo.CreateUsers(ctx, names)
+	assert.NoError(t, err)
+	john, err := repo.CreateUser(ctx, "John Doe")
+	assert.NoError(t, err)
+
+	matches, total, err := repo.FuzzySearchUsers(ctx, "John Doe", 0.3, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1501), total)
+	assert.Equal(t, john.ID, matches[0].User.ID)
+
+	last, total, err := repo.FuzzySearchUsers(ctx, "John Doe", 0.3, 1495, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1501), total)
+	assert.Len(t, last, 6)
+}
+
+func TestUserRepo_FuzzySearchUsers_SingleWriter(t *testing.T) {
+	ctx := context.Background()
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "fuzzy.db"),
+		db.WithLogger(logger.Discard), db.WithJournalMode("WAL"), db.WithSingleWriter())
+	assert.NoError(t, err)
+	defer db.Close(gormDB)
+	repo := repository.NewUserRepo(gormDB)
+
+	john, err := repo.CreateUser(ctx, "John Doe")
+	assert.NoError(t, err)
+
+	// Hold the only write connection: the search must be served by the read
+	// pool rather than queue behind the writer.
+	tx := gormDB.Begin()
+	defer tx.Rollback()
+	assert.NoError(t, tx.Create(&model.User{Name: "John Doe", CreatedAt: 1, UpdatedAt: 1}).Error)
+
+	timeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	matches, total, err := repo.FuzzySearchUsers(timeout, "Jon Doe", 0.3, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, john.ID, matches[0].User.ID)
+}
+
+func TestUserRepo_CreateUsers(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := repository.NewUserRepo(db)
+
+	tests := []struct {
+		name  string
+		names []string
+	}{
+		{"single", []string{"Alice"}},
+		{"several", []string{"Bob", "Carol", "Dave"}},
+		{"spans insert batches", make([]string, 450)},
+		{"empty", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.names {
+				if tt.names[i] == "" {
+					tt.names[i] = "Bulk User " + strconv.Itoa(i)
+				}
+			}
+
+			users, err := repo.CreateUsers(ctx, tt.names)
+			assert.NoError(t, err)
+			assert.Len(t, users, len(tt.names))
+
+			ids := make([]uint64, len(users))
+			for i, u := range users {
+				assert.NotZero(t, u.ID)
+				assert.Equal(t, tt.names[i], u.Name)
+				assert.Equal(t, uint64(1), u.Version)
+				ids[i] = u.ID
+			}
+
+			stored, err := repo.GetUserByIDs(ctx, ids)
+			assert.NoError(t, err)
+			assert.Len(t, stored, len(ids))
+		})
+	}
+
+	t.Run("indexed for search", func(t *testing.T) {
+		_, total, err := repo.SearchUsers(ctx, "carol", 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+
+		_, total, err = repo.FuzzySearchUsers(ctx, "Bulk Usr 449", 0.5, 0, 10)
+		assert.NoError(t, err)
+		assert.NotZero(t, total)
+	})
+}
+
+func TestUserRepo_GetUserByIDs_Chunked(t *testing.T) {
+	ctx := context.Background()
+
+	// Parallel reads need a shared file database: each connection to
+	// ":memory:" would otherwise see its own empty database.
+	gormDB, err := db.InitDB(filepath.Join(t.TempDir(), "chunked.db"))
+	assert.NoError(t, err)
+
+	seed := repository.NewUserRepo(gormDB)
+	created, err := seed.CreateUsers(ctx, []string{"A", "B", "C", "D", "E", "F", "G"})
+	assert.NoError(t, err)
+
+	// Far more IDs than SQLite accepts as bound parameters in one statement.
+	ids := make([]uint64, 0, 40000)
+	for i := uint64(1); i <= 40000; i++ {
+		ids = append(ids, 100000+i)
+	}
+	for _, u := range created {
+		ids = append(ids, u.ID)
+	}
+
+	tests := []struct {
+		name string
+		opts []repository.Option
+	}{
+		{"default chunks", nil},
+		{"small chunks", []repository.Option{repository.WithIDChunkSize(250)}},
+		{"parallel chunks", []repository.Option{repository.WithIDChunkSize(1000), repository.WithParallelReads(4)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewUserRepo(gormDB, tt.opts...)
+
+			users, err := repo.GetUserByIDs(ctx, ids)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, created, users)
+		})
+	}
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		repo := repository.NewUserRepo(gormDB, repository.WithIDChunkSize(1000), repository.WithParallelReads(2))
+		_, err := repo.GetUserByIDs(canceled, ids)
+		assert.Error(t, err)
+	})
+}
+
+func TestUserRepo_ErrorKinds(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "kinds.db")
+	gormDB, err := db.InitDB(path + "?_pragma=busy_timeout(0)")
+	if err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	repo := repository.NewUserRepo(gormDB)
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := repo.GetUser(ctx, 9999)
+		assert.ErrorIs(t, err, repository.ErrUserNotFound)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+		defer cancel()
+
+		_, err := repo.GetAllUsers(expired, 0, 10)
+		assert.ErrorIs(t, err, repository.ErrTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		// Hold the write lock from a second connection so the write below
+		// fails with SQLITE_BUSY.
+		other, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("failed to open second connection: %v", err)
+		}
+		lock := other.Begin()
+		assert.NoError(t, lock.Exec("INSERT INTO users (name, created_at, updated_at) VALUES ('Lock', 1, 1)").Error)
+		defer lock.Rollback()
+
+		_, err = repo.CreateUser(ctx, "Blocked")
+		assert.ErrorIs(t, err, repository.ErrUnavailable)
+		assert.NotErrorIs(t, err, repository.ErrConflict)
+	})
+
+	t.Run("kinds are distinct", func(t *testing.T) {
+		assert.ErrorIs(t, repository.ErrVersionConflict, repository.ErrConflict)
+		assert.ErrorIs(t, repository.ErrUserNotDeleted, repository.ErrConflict)
+		assert.NotErrorIs(t, repository.ErrUserNotFound, repository.ErrConflict)
+	})
+}
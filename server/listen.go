@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+)
+
+// Environment variables used to pass a listening socket to this process.
+const (
+	// envListenFD names the descriptor of a socket handed over by a previous
+	// instance of the service during an upgrade.
+	envListenFD = "USER_SERVICE_LISTEN_FD"
+	// envReadyFD names the pipe on which a handed-over instance reports
+	// that it is serving.
+	envReadyFD = "USER_SERVICE_READY_FD"
+)
+
+// systemd socket activation passes sockets starting at this descriptor.
+const sdListenFDsStart = 3
+
+// Listen returns the socket to serve on. In order of preference it is the
+// socket handed over by a previous instance during an upgrade, the first
+// socket passed by systemd socket activation, or a new TCP socket on addr.
+func Listen(addr string) (net.Listener, error) {
+	if fd, ok := inheritedFD(os.Getenv, os.Getpid()); ok {
+		unsetListenEnv()
+		return fileListener(fd)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// inheritedFD reports which descriptor holds an inherited listening socket.
+// systemd's LISTEN_FDS is only honoured when LISTEN_PID names this process,
+// as sd_listen_fds(3) requires.
+func inheritedFD(getenv func(string) string, pid int) (uintptr, bool) {
+	if fd, err := strconv.Atoi(getenv(envListenFD)); err == nil && fd >= sdListenFDsStart {
+		return uintptr(fd), true
+	}
+
+	listenPID, err := strconv.Atoi(getenv("LISTEN_PID"))
+	if err != nil || listenPID != pid {
+		return 0, false
+	}
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return sdListenFDsStart, true
+}
+
+// unsetListenEnv keeps inherited-socket variables from leaking into child
+// processes, which would otherwise try to reuse descriptors they do not own.
+func unsetListenEnv() {
+	for _, key := range []string{envListenFD, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(key)
+	}
+}
+
+func fileListener(fd uintptr) (net.Listener, error) {
+	f := os.NewFile(fd, "listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
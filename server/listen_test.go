@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInheritedFD(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		wantFD uintptr
+		wantOK bool
+	}{
+		{"nothing inherited", nil, 0, false},
+		{"handed over by previous instance", map[string]string{envListenFD: "3"}, 3, true},
+		{"invalid handover descriptor", map[string]string{envListenFD: "1"}, 0, false},
+		{"systemd socket activation", map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "1"}, 3, true},
+		{"systemd sockets meant for another process", map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "1"}, 0, false},
+		{"systemd without sockets", map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "0"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, ok := inheritedFD(func(key string) string { return tt.env[key] }, 42)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantFD, fd)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package server runs the HTTP server and shuts it down gracefully.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Serve accepts connections on ln until ctx is done, then stops accepting new
+// connections and waits up to drainTimeout for in-flight requests to finish.
+// Requests still running after drainTimeout are cut off and Serve returns an
+// error. Serve returns nil after a clean drain. It wraps srv.ConnState to
+// track connections.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, drainTimeout time.Duration) error {
+	conns := newConnTracker()
+	hook := srv.ConnState
+	srv.ConnState = func(c net.Conn, state http.ConnState) {
+		conns.update(c, state)
+		if hook != nil {
+			hook(c, state)
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server stopped on its own, e.g. the listener failed.
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	// http.Server drops requests it reads after Shutdown starts, so first stop
+	// accepting and let connections that were already accepted send their
+	// request. The socket stays open if another process shares it.
+	srv.SetKeepAlivesEnabled(false)
+	_ = ln.Close()
+	if err := <-errCh; err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	conns.waitUnread(drainCtx)
+
+	if err := srv.Shutdown(drainCtx); err != nil {
+		_ = srv.Close()
+		return fmt.Errorf("drain in-flight requests: %w", err)
+	}
+	return nil
+}
+
+// connTracker records connections that have not sent a request yet.
+type connTracker struct {
+	mu     sync.Mutex
+	unread map[net.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{unread: make(map[net.Conn]struct{})}
+}
+
+func (t *connTracker) update(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state == http.StateNew {
+		t.unread[c] = struct{}{}
+	} else {
+		delete(t.unread, c)
+	}
+}
+
+// waitUnread blocks until every tracked connection has sent a request or
+// closed, or ctx is done.
+func (t *connTracker) waitUnread(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		n := len(t.unread)
+		t.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+	"user-service/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowServer returns a server whose handler signals started and then blocks
+// until release is closed.
+func slowServer(started chan<- struct{}, release <-chan struct{}) *http.Server {
+	return &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})}
+}
+
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	return ln
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	ln := listen(t)
+	addr := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, slowServer(started, release), ln, 5*time.Second)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(addr)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+	// New connections are refused once shutdown has begun.
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the in-flight request finished: %v", err)
+	default:
+	}
+
+	close(release)
+	got := <-inFlight
+	assert.NoError(t, got.err)
+	assert.Equal(t, "done", got.body)
+	assert.NoError(t, <-served)
+}
+
+func TestServe_AnswersAcceptedConnections(t *testing.T) {
+	accepted := make(chan struct{}, 1)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "done")
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				accepted <- struct{}{}
+			}
+		},
+	}
+	ln := listen(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, srv, ln, 5*time.Second)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	<-accepted
+
+	// The request arrives after shutdown has begun on a connection that was
+	// accepted before it.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	assert.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "done", string(body))
+	}
+	assert.NoError(t, <-served)
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	ln := listen(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, slowServer(started, release), ln, 50*time.Millisecond)
+	}()
+
+	go func() {
+		if resp, err := http.Get("http://" + ln.Addr().String()); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	cancel()
+	err := <-served
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServe_ListenerFailure(t *testing.T) {
+	ln := listen(t)
+	ln.Close()
+
+	err := server.Serve(context.Background(), &http.Server{}, ln, time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// UpgradeSignals is empty where descriptors cannot be handed to a new process.
+var UpgradeSignals []os.Signal
+
+// Upgrade is not supported on this platform.
+func Upgrade(ln net.Listener, readyTimeout time.Duration) (int, error) {
+	return 0, errors.New("upgrade: not supported on this platform")
+}
+
+// Ready does nothing on this platform.
+func Ready() error {
+	return nil
+}
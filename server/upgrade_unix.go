@@ -0,0 +1,120 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// UpgradeSignals are the signals that ask the service to re-execute itself
+// without closing its listening socket.
+var UpgradeSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// Upgrade starts a new instance of the running binary, with the same
+// arguments, that serves on ln, waits up to readyTimeout for it to report
+// through Ready that it is serving, and returns its process ID. On success
+// the caller should drain and exit; both instances accept connections on ln
+// until it does, so none are refused. On failure the new instance is killed
+// and the caller keeps serving.
+func Upgrade(ln net.Listener, readyTimeout time.Duration) (int, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return 0, fmt.Errorf("upgrade: listener %T cannot be handed over", ln)
+	}
+	lnFile, err := filer.File()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: %w", err)
+	}
+	defer lnFile.Close()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: %w", err)
+	}
+	defer readyR.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		readyW.Close()
+		return 0, fmt.Errorf("upgrade: %w", err)
+	}
+
+	// ExtraFiles[i] becomes descriptor 3+i in the new process.
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{lnFile, readyW}
+	cmd.Env = append(childEnv(),
+		envListenFD+"="+strconv.Itoa(sdListenFDsStart),
+		envReadyFD+"="+strconv.Itoa(sdListenFDsStart+1),
+	)
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: start %s: %w", exe, err)
+	}
+
+	if err := waitReady(readyR, readyTimeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return 0, fmt.Errorf("upgrade: new process %d did not become ready: %w", cmd.Process.Pid, err)
+	}
+
+	// The new process outlives this one; nothing will wait for it here.
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	return pid, nil
+}
+
+// childEnv is this process's environment without inherited-socket variables.
+func childEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		switch key, _, _ := strings.Cut(kv, "="); key {
+		case envListenFD, envReadyFD, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES":
+		default:
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// waitReady waits for a byte on r. The new process closing its end without
+// writing, e.g. because it exited, counts as a failure.
+func waitReady(r *os.File, timeout time.Duration) error {
+	if err := r.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	buf := make([]byte, 1)
+	if _, err := r.Read(buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("exited before reporting ready")
+		}
+		return err
+	}
+	return nil
+}
+
+// Ready tells the instance that started this one through Upgrade that it is
+// now serving, so the old instance can drain and exit. It does nothing when
+// the process was not started by Upgrade.
+func Ready() error {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFD))
+	if err != nil {
+		return nil
+	}
+	os.Unsetenv(envReadyFD)
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	_, err = f.Write([]byte{1})
+	return err
+}
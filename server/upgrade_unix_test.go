@@ -0,0 +1,127 @@
+//go:build unix
+
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+	"user-service/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// childModeEnv makes the test binary act as the process started by Upgrade.
+const childModeEnv = "SERVER_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(childModeEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "fail":
+		os.Exit(3)
+	default:
+		os.Exit(runChild())
+	}
+}
+
+// runChild serves "child" on the inherited listener until SIGTERM.
+func runChild() int {
+	ln, err := server.Listen("")
+	if err != nil {
+		return 1
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "child")
+	})}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if err := server.Ready(); err != nil {
+		return 1
+	}
+	if err := server.Serve(ctx, srv, ln, time.Second); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// get fetches addr over a fresh connection so each call reaches whichever
+// process accepts it.
+func get(t *testing.T, addr string) string {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + addr)
+	if err != nil {
+		t.Fatalf("GET %s: %v", addr, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestUpgrade_HandsOverListener(t *testing.T) {
+	t.Setenv(childModeEnv, "serve")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	parent := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "parent")
+	})}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, parent, ln, time.Second) }()
+	assert.Equal(t, "parent", get(t, addr))
+
+	pid, err := server.Upgrade(ln, 10*time.Second)
+	if !assert.NoError(t, err) {
+		cancel()
+		return
+	}
+	defer syscall.Kill(pid, syscall.SIGTERM)
+
+	// The old process drains and closes its copy of the socket; the port
+	// keeps accepting through the new one.
+	cancel()
+	assert.NoError(t, <-served)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "child", get(t, addr))
+	}
+}
+
+func TestUpgrade_FailedChildKeepsServing(t *testing.T) {
+	t.Setenv(childModeEnv, "fail")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	_, err = server.Upgrade(ln, 10*time.Second)
+	assert.ErrorContains(t, err, "exited before reporting ready")
+
+	// The listener is still usable by this process.
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	if conn != nil {
+		conn.Close()
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"user-service/model"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor is malformed or has
+// been tampered with.
+var ErrInvalidCursor error = validationError("invalid cursor")
+
+// cursorCodec turns listing positions into opaque, signed tokens.
+type cursorCodec struct {
+	secret []byte
+}
+
+// newCursorCodec returns a codec signing with secret, or with a random
+// per-process key when secret is empty.
+func newCursorCodec(secret []byte) cursorCodec {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	return cursorCodec{secret: secret}
+}
+
+// encode serializes and signs a cursor as "<payload>.<signature>".
+func (c cursorCodec) encode(cursor model.UserCursor) string {
+	payload, _ := json.Marshal(cursor)
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + base64.RawURLEncoding.EncodeToString(c.sign(p))
+}
+
+// decode verifies and parses a token produced by encode.
+func (c cursorCodec) decode(token string) (model.UserCursor, error) {
+	var cursor model.UserCursor
+
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return cursor, ErrInvalidCursor
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, c.sign(p)) {
+		return cursor, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func (c cursorCodec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// after returns a cursor pointing past the last (oldest) user of a page.
+func (c cursorCodec) after(users []model.User) string {
+	last := users[len(users)-1]
+	return c.encode(model.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+}
+
+// before returns a cursor pointing ahead of the first (newest) user of a page.
+func (c cursorCodec) before(users []model.User) string {
+	first := users[0]
+	return c.encode(model.UserCursor{CreatedAt: first.CreatedAt, ID: first.ID, Before: true})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"user-service/repository"
+)
+
+// Error kinds, shared with the repository layer. Every error returned by a
+// UserService matches at most one of them under errors.Is.
+var (
+	// ErrNotFound means the requested user does not exist.
+	ErrNotFound = repository.ErrNotFound
+	// ErrConflict means the request clashes with the current state of the user.
+	ErrConflict = repository.ErrConflict
+	// ErrValidation means the caller's input is invalid.
+	ErrValidation = repository.ErrValidation
+	// ErrUnavailable means the database could not serve the request right now.
+	ErrUnavailable = repository.ErrUnavailable
+	// ErrTimeout means the request ran out of time before the database answered.
+	ErrTimeout = repository.ErrTimeout
+)
+
+// ErrInvalidInput narrows ErrValidation to the errors the service builds from
+// checking the caller's input, whose text is safe to show the caller. Inputs
+// rejected by a database constraint match only ErrValidation.
+var ErrInvalidInput = errors.New("invalid input")
+
+var (
+	// ErrInvalidPagination is returned for out-of-range page numbers or sizes.
+	ErrInvalidPagination error = validationError("invalid pagination")
+	// ErrInvalidQuery is returned for an empty search query or unknown search mode.
+	ErrInvalidQuery error = validationError("invalid search query")
+	// ErrInvalidUser is returned when user input fails validation.
+	ErrInvalidUser error = validationError("invalid user")
+	// ErrBatchTooLarge is returned when a batch request exceeds its size limit.
+	ErrBatchTooLarge error = validationError("batch too large")
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the requested ID.
+	ErrUserNotFound = repository.ErrUserNotFound
+	// ErrVersionConflict is returned when the caller's version no longer matches the stored user.
+	ErrVersionConflict = repository.ErrVersionConflict
+	// ErrUserNotDeleted is returned when restoring a user that is not deleted.
+	ErrUserNotDeleted = repository.ErrUserNotDeleted
+	// ErrRetentionExpired is returned when a deleted user can no longer be restored.
+	ErrRetentionExpired = repository.ErrRetentionExpired
+)
+
+// FieldError is a validation error about a single field of the input, such
+// as "name must be at most 255 characters".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string { return e.Field + " " + e.Message }
+
+func (e *FieldError) Is(target error) bool {
+	return target == ErrValidation || target == ErrInvalidInput
+}
+
+// validationError is a specific sentinel error that also matches ErrValidation
+// and ErrInvalidInput.
+type validationError string
+
+func (e validationError) Error() string { return string(e) }
+
+func (e validationError) Is(target error) bool {
+	return target == ErrValidation || target == ErrInvalidInput
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"user-service/model"
+	"user-service/repository"
+)
+
+// WithUserLoader merges concurrent GetUser calls. Calls for an ID that is
+// already being loaded wait for that load, and calls for other IDs that
+// arrive within wait of each other are loaded with one GetUserByIDs query of
+// up to maxBatch IDs.
+func WithUserLoader(wait time.Duration, maxBatch int) Option {
+	return func(s *userServiceImpl) {
+		s.loader = &userLoader{wait: wait, maxBatch: maxBatch, calls: make(map[uint64]*loadCall)}
+	}
+}
+
+// userLoader is a dataloader for users by ID.
+type userLoader struct {
+	repo     repository.UserRepository
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	calls   map[uint64]*loadCall // queued or being loaded
+	pending *loadBatch
+}
+
+// loadBatch is a group of IDs loaded by one query.
+type loadBatch struct {
+	calls []*loadCall
+	timer *time.Timer
+
+	// ctx is the context of the first caller, whose request ID and span the
+	// query runs under. deadline is the latest deadline of the callers, or
+	// zero if one of them has none.
+	ctx        context.Context
+	deadline   time.Time
+	noDeadline bool
+}
+
+// loadCall is the load of one ID, shared by every GetUser call for it.
+type loadCall struct {
+	id    uint64
+	batch *loadBatch
+	done  chan struct{}
+	user  model.User
+	err   error
+}
+
+// wait extends the deadline of b so the query runs for as long as a caller
+// with ctx is still waiting.
+func (b *loadBatch) wait(ctx context.Context) {
+	if b.noDeadline {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	switch {
+	case !ok:
+		b.noDeadline, b.deadline = true, time.Time{}
+	case deadline.After(b.deadline):
+		b.deadline = deadline
+	}
+}
+
+// load returns the user with the given ID, joining a load of the same ID if
+// one is queued or running. If ctx ends first, load returns its error and
+// the load carries on for the other callers until the last of them gives up.
+func (l *userLoader) load(ctx context.Context, id uint64) (model.User, error) {
+	l.mu.Lock()
+	call, ok := l.calls[id]
+	var full *loadBatch
+	switch {
+	case !ok:
+		b := l.pending
+		if b == nil {
+			b = &loadBatch{ctx: ctx}
+			b.timer = time.AfterFunc(l.wait, func() { l.dispatch(b) })
+			l.pending = b
+		}
+		call = &loadCall{id: id, batch: b, done: make(chan struct{})}
+		l.calls[id] = call
+		b.calls = append(b.calls, call)
+		b.wait(ctx)
+		if len(b.calls) >= l.maxBatch {
+			full = b
+		}
+	case call.batch == l.pending:
+		call.batch.wait(ctx)
+	}
+	l.mu.Unlock()
+
+	if full != nil {
+		l.dispatch(full)
+	}
+
+	select {
+	case <-call.done:
+		return call.user, call.err
+	case <-ctx.Done():
+		return model.User{}, ctx.Err()
+	}
+}
+
+// dispatch loads b, unless the timer and a full batch both tried and the
+// other got there first.
+func (l *userLoader) dispatch(b *loadBatch) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	b.timer.Stop()
+	l.mu.Unlock()
+
+	ids := make([]uint64, len(b.calls))
+	for i, call := range b.calls {
+		ids[i] = call.id
+	}
+	// The load is shared by callers with their own contexts, so one caller
+	// giving up does not cancel it for the others.
+	ctx := context.WithoutCancel(b.ctx)
+	if !b.noDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, b.deadline)
+		defer cancel()
+	}
+	users, err := l.repo.GetUserByIDs(ctx, ids)
+	byID := make(map[uint64]model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, call := range b.calls {
+		delete(l.calls, call.id)
+		user, ok := byID[call.id]
+		switch {
+		case err != nil:
+			call.err = err
+		case !ok:
+			call.err = repository.ErrUserNotFound
+		default:
+			call.user = user
+		}
+		close(call.done)
+	}
+}
@@ -0,0 +1,198 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-service/logging"
+	"user-service/mocks"
+	"user-service/model"
+	"user-service/repository"
+	"user-service/service"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// getConcurrently calls GetUser for every ID at once and returns the results
+// in the order of ids.
+func getConcurrently(ctx context.Context, svc service.UserService, ids []uint64) ([]model.User, []error) {
+	users := make([]model.User, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users[i], errs[i] = svc.GetUser(ctx, id)
+		}()
+	}
+	wg.Wait()
+	return users, errs
+}
+
+func TestUserLoader_MergesConcurrentGets(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(20*time.Millisecond, 100))
+
+	var loaded []uint64
+	mockRepo.EXPECT().GetUserByIDs(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, ids []uint64) ([]model.User, error) {
+			loaded = ids
+			var users []model.User
+			for _, id := range ids {
+				if id != 10 {
+					users = append(users, model.User{ID: id})
+				}
+			}
+			return users, nil
+		}).Times(1)
+
+	// 50 calls for 10 IDs, one of which has no user.
+	ids := make([]uint64, 50)
+	for i := range ids {
+		ids[i] = uint64(i%10 + 1)
+	}
+	users, errs := getConcurrently(ctx, svc, ids)
+
+	assert.ElementsMatch(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, loaded)
+	for i, id := range ids {
+		if id == 10 {
+			assert.ErrorIs(t, errs[i], repository.ErrUserNotFound)
+			continue
+		}
+		assert.NoError(t, errs[i])
+		assert.Equal(t, id, users[i].ID)
+	}
+}
+
+func TestUserLoader_FullBatchLoadsAtOnce(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(time.Hour, 3))
+
+	mockRepo.EXPECT().GetUserByIDs(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, ids []uint64) ([]model.User, error) {
+			assert.Len(t, ids, 3)
+			users := make([]model.User, len(ids))
+			for i, id := range ids {
+				users[i] = model.User{ID: id}
+			}
+			return users, nil
+		}).Times(2)
+
+	_, errs := getConcurrently(ctx, svc, []uint64{1, 2, 3, 4, 5, 6})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestUserLoader_JoinsLoadInFlight(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(time.Hour, 1))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	mockRepo.EXPECT().GetUserByIDs(gomock.Any(), []uint64{7}).DoAndReturn(
+		func(context.Context, []uint64) ([]model.User, error) {
+			close(started)
+			<-release
+			return nil, repository.ErrUnavailable
+		}).Times(1)
+
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	get := func() {
+		defer wg.Done()
+		if _, err := svc.GetUser(ctx, 7); assert.ErrorIs(t, err, repository.ErrUnavailable) {
+			failed.Add(1)
+		}
+	}
+	wg.Add(1)
+	go get()
+	<-started
+	wg.Add(2)
+	go get()
+	go get()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(3), failed.Load(), "every caller gets the shared error")
+}
+
+func TestUserLoader_CancelledCallerDoesNotCancelLoad(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(20*time.Millisecond, 100))
+
+	mockRepo.EXPECT().GetUserByIDs(gomock.Any(), []uint64{1}).Return([]model.User{{ID: 1}}, nil)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := svc.GetUser(cancelled, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The abandoned call's load is still queued, and the next caller joins it.
+	user, err := svc.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), user.ID)
+}
+
+func TestUserLoader_RunsOnCallerContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(50*time.Millisecond, 100))
+
+	first, cancel := context.WithTimeout(logging.WithRequestID(context.Background(), "req-1"), time.Minute)
+	defer cancel()
+	later := time.Now().Add(time.Hour)
+	second, cancel := context.WithDeadline(logging.WithRequestID(context.Background(), "req-2"), later)
+	defer cancel()
+
+	mockRepo.EXPECT().GetUserByIDs(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ids []uint64) ([]model.User, error) {
+			// The query runs under the first caller's request ID, for as long
+			// as any caller still waits.
+			assert.Equal(t, "req-1", logging.RequestID(ctx))
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, later, deadline, time.Millisecond)
+			users := make([]model.User, len(ids))
+			for i, id := range ids {
+				users[i] = model.User{ID: id}
+			}
+			return users, nil
+		}).Times(1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := svc.GetUser(first, 1)
+		assert.NoError(t, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_, err := svc.GetUser(second, 2)
+	assert.NoError(t, err)
+	wg.Wait()
+}
+
+func TestUserLoader_WithDeletedBypassesLoader(t *testing.T) {
+	ctx := service.WithDeleted(context.Background())
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithUserLoader(time.Hour, 100))
+
+	deletedAt := int64(1)
+	mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(model.User{ID: 1, DeletedAt: &deletedAt}, nil)
+	user, err := svc.GetUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.NotNil(t, user.DeletedAt)
+}
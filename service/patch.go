@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"user-service/model"
+)
+
+// ErrInvalidPatch is returned when a patch document is malformed or touches
+// fields that clients are not allowed to change.
+var ErrInvalidPatch error = validationError("invalid patch")
+
+// mutableFields lists the user document members a patch may modify.
+var mutableFields = map[string]bool{
+	"name": true,
+}
+
+// patchOp is a single RFC 6902 operation.
+type patchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyPatch applies a merge patch or JSON patch to user and returns the result.
+// Read-only members (id, timestamps, version) may be tested but never changed.
+func applyPatch(user model.User, patch model.UserPatch) (model.User, error) {
+	original, err := toDocument(user)
+	if err != nil {
+		return model.User{}, err
+	}
+	doc := make(map[string]interface{}, len(original))
+	for key, value := range original {
+		doc[key] = value
+	}
+
+	switch patch.Format {
+	case model.MergePatch:
+		err = applyMergePatch(doc, patch.Body)
+	case model.JSONPatch:
+		err = applyJSONPatch(doc, patch.Body)
+	default:
+		err = fmt.Errorf("%w: unsupported patch format", ErrInvalidPatch)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	for key, value := range doc {
+		if mutableFields[key] {
+			continue
+		}
+		if orig, ok := original[key]; !ok || !reflect.DeepEqual(orig, value) {
+			return model.User{}, fmt.Errorf("%w: field %q cannot be modified", ErrInvalidPatch, key)
+		}
+	}
+	for key := range original {
+		if _, ok := doc[key]; !ok {
+			return model.User{}, fmt.Errorf("%w: field %q cannot be removed", ErrInvalidPatch, key)
+		}
+	}
+
+	name, ok := doc["name"].(string)
+	if !ok {
+		return model.User{}, fmt.Errorf("%w: %w", ErrInvalidPatch, &FieldError{Field: "name", Message: "must be a string"})
+	}
+	if err := validateName(name); err != nil {
+		return model.User{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	user.Name = name
+	return user, nil
+}
+
+// applyMergePatch applies an RFC 7396 merge patch to the top-level document.
+func applyMergePatch(doc map[string]interface{}, body []byte) error {
+	var patch map[string]interface{}
+	if err := decodeJSON(body, &patch); err != nil || patch == nil {
+		return fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		doc[key] = value
+	}
+	return nil
+}
+
+// applyJSONPatch applies an RFC 6902 operation list to the top-level document.
+func applyJSONPatch(doc map[string]interface{}, body []byte) error {
+	var ops []patchOp
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return fmt.Errorf("%w: JSON patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	for i, op := range ops {
+		key, err := pointerKey(op.Path)
+		if err != nil {
+			return fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			value, err := opValue(op)
+			if err != nil {
+				return fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+			if _, exists := doc[key]; op.Op == "replace" && !exists {
+				return fmt.Errorf("%w: operation %d: path %q does not exist", ErrInvalidPatch, i, op.Path)
+			}
+			doc[key] = value
+		case "remove":
+			if _, exists := doc[key]; !exists {
+				return fmt.Errorf("%w: operation %d: path %q does not exist", ErrInvalidPatch, i, op.Path)
+			}
+			delete(doc, key)
+		case "test":
+			value, err := opValue(op)
+			if err != nil {
+				return fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+			if !reflect.DeepEqual(doc[key], value) {
+				return fmt.Errorf("%w: operation %d: test failed for path %q", ErrInvalidPatch, i, op.Path)
+			}
+		case "copy", "move":
+			from, err := pointerKey(op.From)
+			if err != nil {
+				return fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+			value, exists := doc[from]
+			if !exists {
+				return fmt.Errorf("%w: operation %d: path %q does not exist", ErrInvalidPatch, i, op.From)
+			}
+			if op.Op == "move" {
+				delete(doc, from)
+			}
+			doc[key] = value
+		default:
+			return fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+	}
+	return nil
+}
+
+// pointerKey resolves a JSON pointer to a top-level member name.
+func pointerKey(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("unsupported path %q", pointer)
+	}
+	key := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+	return key, nil
+}
+
+// opValue decodes the value member of a patch operation.
+func opValue(op patchOp) (interface{}, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("missing value for %q", op.Op)
+	}
+	var value interface{}
+	if err := decodeJSON(*op.Value, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// toDocument converts a user into a generic JSON document.
+func toDocument(user model.User) (map[string]interface{}, error) {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := decodeJSON(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeJSON decodes numbers as json.Number so values compare exactly.
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
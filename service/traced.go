@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"user-service/model"
+	"user-service/tracing"
+)
+
+// tracedService runs every call to another UserService in a span.
+type tracedService struct {
+	inner  UserService
+	tracer *tracing.Tracer
+}
+
+// NewTracedService wraps inner so that each call runs in a span named
+// UserService.<Method>, a child of the span in the caller's context, and
+// failed calls mark their span as failed.
+func NewTracedService(inner UserService, tracer *tracing.Tracer) UserService {
+	return &tracedService{inner: inner, tracer: tracer}
+}
+
+func (s *tracedService) CreateUser(ctx context.Context, name string) (model.User, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	user, err := s.inner.CreateUser(ctx, name)
+	span.RecordError(err)
+	if err == nil {
+		span.SetAttributes(tracing.Int64("user.id", int64(user.ID)))
+	}
+	return user, err
+}
+
+func (s *tracedService) CreateUsers(ctx context.Context, names []string, mode model.BulkMode) ([]model.BulkCreateResult, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.CreateUsers", tracing.WithAttributes(
+		tracing.Int("users.count", len(names)),
+		tracing.String("bulk.mode", string(mode)),
+	))
+	defer span.End()
+
+	results, err := s.inner.CreateUsers(ctx, names, mode)
+	span.RecordError(err)
+	return results, err
+}
+
+func (s *tracedService) GetUser(ctx context.Context, id uint64) (model.User, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetUser", tracing.WithAttributes(tracing.Int64("user.id", int64(id))))
+	defer span.End()
+
+	user, err := s.inner.GetUser(ctx, id)
+	span.RecordError(err)
+	return user, err
+}
+
+func (s *tracedService) GetAllUsers(ctx context.Context, page, size int) (model.UserPage, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetAllUsers", tracing.WithAttributes(
+		tracing.Int("page.num", page),
+		tracing.Int("page.size", size),
+	))
+	defer span.End()
+
+	result, err := s.inner.GetAllUsers(ctx, page, size)
+	span.RecordError(err)
+	return result, err
+}
+
+func (s *tracedService) ListUsersByCursor(ctx context.Context, cursor string, size int) (model.UserPage, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.ListUsersByCursor", tracing.WithAttributes(tracing.Int("page.size", size)))
+	defer span.End()
+
+	result, err := s.inner.ListUsersByCursor(ctx, cursor, size)
+	span.RecordError(err)
+	return result, err
+}
+
+func (s *tracedService) SearchUsers(ctx context.Context, query string, mode model.SearchMode, page, size int) (model.UserSearchPage, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.SearchUsers", tracing.WithAttributes(
+		tracing.String("search.mode", string(mode)),
+		tracing.Int("page.num", page),
+		tracing.Int("page.size", size),
+	))
+	defer span.End()
+
+	result, err := s.inner.SearchUsers(ctx, query, mode, page, size)
+	span.RecordError(err)
+	return result, err
+}
+
+func (s *tracedService) GetUsersByIDs(ctx context.Context, ids []uint64) (model.BatchFetchUsersResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetUsersByIDs", tracing.WithAttributes(tracing.Int("users.count", len(ids))))
+	defer span.End()
+
+	resp, err := s.inner.GetUsersByIDs(ctx, ids)
+	span.RecordError(err)
+	return resp, err
+}
+
+func (s *tracedService) UpdateUser(ctx context.Context, id uint64, patch model.UserPatch, expectedVersion uint64) (model.User, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.UpdateUser", tracing.WithAttributes(tracing.Int64("user.id", int64(id))))
+	defer span.End()
+
+	user, err := s.inner.UpdateUser(ctx, id, patch, expectedVersion)
+	span.RecordError(err)
+	return user, err
+}
+
+func (s *tracedService) DeleteUser(ctx context.Context, id uint64) error {
+	ctx, span := s.tracer.Start(ctx, "UserService.DeleteUser", tracing.WithAttributes(tracing.Int64("user.id", int64(id))))
+	defer span.End()
+
+	err := s.inner.DeleteUser(ctx, id)
+	span.RecordError(err)
+	return err
+}
+
+func (s *tracedService) RestoreUser(ctx context.Context, id uint64) (model.User, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.RestoreUser", tracing.WithAttributes(tracing.Int64("user.id", int64(id))))
+	defer span.End()
+
+	user, err := s.inner.RestoreUser(ctx, id)
+	span.RecordError(err)
+	return user, err
+}
+
+func (s *tracedService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.PurgeDeletedUsers")
+	defer span.End()
+
+	purged, err := s.inner.PurgeDeletedUsers(ctx)
+	span.RecordError(err)
+	if err == nil {
+		span.SetAttributes(tracing.Int64("users.purged", purged))
+	}
+	return purged, err
+}
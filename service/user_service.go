@@ -2,6 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
 	"user-service/model"
 	"user-service/repository"
 )
@@ -11,45 +17,445 @@ import (
 //go:generate mockgen -source=user_service.go -destination=../mocks/mock_user_service.go -package=mocks
 type UserService interface {
 	CreateUser(ctx context.Context, name string) (model.User, error)
+	CreateUsers(ctx context.Context, names []string, mode model.BulkMode) ([]model.BulkCreateResult, error)
 	GetUser(ctx context.Context, id uint64) (model.User, error)
-	GetAllUsers(ctx context.Context, page, size int) ([]model.User, error)
-	GetUsersByIDs(ctx context.Context, ids []uint64) ([]model.User, error)
+	GetAllUsers(ctx context.Context, page, size int) (model.UserPage, error)
+	ListUsersByCursor(ctx context.Context, cursor string, size int) (model.UserPage, error)
+	SearchUsers(ctx context.Context, query string, mode model.SearchMode, page, size int) (model.UserSearchPage, error)
+	GetUsersByIDs(ctx context.Context, ids []uint64) (model.BatchFetchUsersResponse, error)
+	UpdateUser(ctx context.Context, id uint64, patch model.UserPatch, expectedVersion uint64) (model.User, error)
+	DeleteUser(ctx context.Context, id uint64) error
+	RestoreUser(ctx context.Context, id uint64) (model.User, error)
+	PurgeDeletedUsers(ctx context.Context) (int64, error)
+}
+
+// DefaultRetention is how long a soft-deleted user can be restored before it
+// becomes eligible for purging.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// DefaultMaxPageSize is the largest page_size accepted by listing calls.
+const DefaultMaxPageSize = 100
+
+// DefaultMaxBulkSize is the largest number of users accepted by one bulk create.
+const DefaultMaxBulkSize = 1000
+
+// MaxNameLength is the longest user name accepted, in characters.
+const MaxNameLength = 255
+
+// DefaultFuzzyThreshold is the minimum trigram similarity for a fuzzy search hit.
+const DefaultFuzzyThreshold = 0.3
+
+// WithDeleted returns a context under which reads also return soft-deleted users.
+func WithDeleted(ctx context.Context) context.Context {
+	return repository.WithDeleted(ctx)
+}
+
+// Option customizes a UserService.
+type Option func(*userServiceImpl)
+
+// WithRetention sets how long soft-deleted users remain restorable.
+func WithRetention(d time.Duration) Option {
+	return func(s *userServiceImpl) {
+		s.retention = d
+	}
+}
+
+// WithCursorSecret sets the key used to sign pagination cursors. Without it a
+// random key is used, so cursors do not survive a restart.
+func WithCursorSecret(secret []byte) Option {
+	return func(s *userServiceImpl) {
+		s.cursors = newCursorCodec(secret)
+	}
+}
+
+// WithMaxPageSize caps the page size accepted by listing calls.
+func WithMaxPageSize(size int) Option {
+	return func(s *userServiceImpl) {
+		s.maxPageSize = size
+	}
+}
+
+// WithFuzzyThreshold sets the minimum similarity, between 0 and 1, for fuzzy search hits.
+func WithFuzzyThreshold(threshold float64) Option {
+	return func(s *userServiceImpl) {
+		s.fuzzyThreshold = threshold
+	}
+}
+
+// WithMaxBulkSize caps the number of users accepted by one bulk create.
+func WithMaxBulkSize(size int) Option {
+	return func(s *userServiceImpl) {
+		s.maxBulkSize = size
+	}
+}
+
+// WithLogger sets the logger that records user writes. Without it nothing is
+// logged.
+func WithLogger(log *slog.Logger) Option {
+	return func(s *userServiceImpl) {
+		s.log = log
+	}
 }
 
 // userServiceImpl is the actual implementation of UserService.
 type userServiceImpl struct {
-	repo repository.UserRepository
+	repo           repository.UserRepository
+	log            *slog.Logger
+	retention      time.Duration
+	cursors        cursorCodec
+	maxPageSize    int
+	maxBulkSize    int
+	fuzzyThreshold float64
+	loader         *userLoader
 }
 
 // NewUserService returns a UserService using the given UserRepository.
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userServiceImpl{repo: repo}
+func NewUserService(repo repository.UserRepository, opts ...Option) UserService {
+	s := &userServiceImpl{
+		repo:           repo,
+		log:            slog.New(slog.DiscardHandler),
+		retention:      DefaultRetention,
+		maxPageSize:    DefaultMaxPageSize,
+		maxBulkSize:    DefaultMaxBulkSize,
+		fuzzyThreshold: DefaultFuzzyThreshold,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.cursors.secret == nil {
+		s.cursors = newCursorCodec(nil)
+	}
+	if s.loader != nil {
+		s.loader.repo = repo
+	}
+	return s
 }
 
 func (s *userServiceImpl) CreateUser(ctx context.Context, name string) (model.User, error) {
-	return s.repo.CreateUser(ctx, name)
+	if err := validateName(name); err != nil {
+		return model.User{}, fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
+	user, err := s.repo.CreateUser(ctx, name)
+	if err != nil {
+		return model.User{}, err
+	}
+	s.log.InfoContext(ctx, "user created", "user_id", user.ID)
+	return user, nil
 }
 
+// CreateUsers creates many users with a single batched insert. In atomic mode
+// any invalid name aborts the whole batch; in partial mode invalid names are
+// reported per item and the rest are still created. A database failure fails
+// the call in either mode.
+func (s *userServiceImpl) CreateUsers(ctx context.Context, names []string, mode model.BulkMode) ([]model.BulkCreateResult, error) {
+	if mode != model.BulkModeAtomic && mode != model.BulkModePartial {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidUser, mode)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: at least one user is required", ErrInvalidUser)
+	}
+	if len(names) > s.maxBulkSize {
+		return nil, fmt.Errorf("%w: at most %d users per request", ErrBatchTooLarge, s.maxBulkSize)
+	}
+
+	results := make([]model.BulkCreateResult, len(names))
+	valid := make([]int, 0, len(names))
+	for i, name := range names {
+		results[i].Index = i
+		if err := validateName(name); err != nil {
+			results[i].Status = model.BulkItemFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if mode == model.BulkModeAtomic && len(valid) < len(names) {
+		for _, i := range valid {
+			results[i].Status = model.BulkItemSkipped
+		}
+		return results, fmt.Errorf("%w: %d of %d users are invalid", ErrInvalidUser, len(names)-len(valid), len(names))
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	validNames := make([]string, len(valid))
+	for j, i := range valid {
+		validNames[j] = names[i]
+	}
+	users, err := s.repo.CreateUsers(ctx, validNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range valid {
+		user := users[j]
+		results[i].Status = model.BulkItemCreated
+		results[i].User = &user
+	}
+	s.log.InfoContext(ctx, "users created", "created", len(users), "failed", len(names)-len(valid))
+	return results, nil
+}
+
+// validateName checks a user name is present and within MaxNameLength.
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return &FieldError{Field: "name", Message: "is required"}
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return &FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxNameLength)}
+	}
+	return nil
+}
+
+// GetUser returns the user with the given ID, through the loader if there is
+// one. Reads that include deleted users are rare and always go to the
+// repository directly.
 func (s *userServiceImpl) GetUser(ctx context.Context, id uint64) (model.User, error) {
-	return s.repo.GetUser(ctx, id)
+	if s.loader == nil || repository.IncludesDeleted(ctx) {
+		return s.repo.GetUser(ctx, id)
+	}
+	return s.loader.load(ctx, id)
+}
+
+// GetAllUsers returns the given 1-based page. The page also carries cursors so
+// clients can switch to keyset pagination from any offset page.
+func (s *userServiceImpl) GetAllUsers(ctx context.Context, page, size int) (model.UserPage, error) {
+	offset, err := s.pageOffset(page, size)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	users, err := s.repo.GetAllUsers(ctx, offset, size+1)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	hasNext := len(users) > size
+	if hasNext {
+		users = users[:size]
+	}
+
+	total, err := s.repo.CountUsers(ctx)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	result := model.UserPage{Users: users, Total: total, Page: page, PageSize: size, HasNext: hasNext}
+	if len(users) > 0 {
+		if hasNext {
+			result.NextCursor = s.cursors.after(users)
+		}
+		if offset > 0 {
+			result.PrevCursor = s.cursors.before(users)
+		}
+	}
+	return result, nil
+}
+
+// ListUsersByCursor returns the page adjacent to an opaque cursor previously
+// returned in a UserPage.
+func (s *userServiceImpl) ListUsersByCursor(ctx context.Context, cursor string, size int) (model.UserPage, error) {
+	if err := s.validatePageSize(size); err != nil {
+		return model.UserPage{}, err
+	}
+
+	pos, err := s.cursors.decode(cursor)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	users, err := s.repo.GetUsersByCursor(ctx, pos, size+1)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	// The extra row sits furthest from the cursor: last when paging forward,
+	// first when paging backward.
+	more := len(users) > size
+	if more {
+		if pos.Before {
+			users = users[1:]
+		} else {
+			users = users[:size]
+		}
+	}
+
+	total, err := s.repo.CountUsers(ctx)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	result := model.UserPage{Users: users, Total: total, PageSize: size}
+	if len(users) > 0 {
+		if more || pos.Before {
+			result.NextCursor = s.cursors.after(users)
+		}
+		if more || !pos.Before {
+			result.PrevCursor = s.cursors.before(users)
+		}
+	}
+	result.HasNext = result.NextCursor != ""
+	return result, nil
+}
+
+// SearchUsers finds users whose names match query, best matches first. In
+// prefix mode every query word must prefix a word of the name; in fuzzy mode
+// names only need to be similar enough to the query.
+func (s *userServiceImpl) SearchUsers(ctx context.Context, query string, mode model.SearchMode, page, size int) (model.UserSearchPage, error) {
+	if strings.TrimSpace(query) == "" {
+		return model.UserSearchPage{}, fmt.Errorf("%w: q must not be empty", ErrInvalidQuery)
+	}
+	offset, err := s.pageOffset(page, size)
+	if err != nil {
+		return model.UserSearchPage{}, err
+	}
+
+	var (
+		matches []model.UserMatch
+		total   int64
+	)
+	switch mode {
+	case model.SearchModePrefix, "":
+		matches, total, err = s.repo.SearchUsers(ctx, query, offset, size)
+	case model.SearchModeFuzzy:
+		matches, total, err = s.repo.FuzzySearchUsers(ctx, query, s.fuzzyThreshold, offset, size)
+	default:
+		return model.UserSearchPage{}, fmt.Errorf("%w: unknown mode %q", ErrInvalidQuery, mode)
+	}
+	if err != nil {
+		return model.UserSearchPage{}, err
+	}
+
+	return model.UserSearchPage{
+		Matches:  matches,
+		Total:    total,
+		Page:     page,
+		PageSize: size,
+		HasNext:  int64(offset+len(matches)) < total,
+	}, nil
+}
+
+// pageOffset returns the offset of the given 1-based page, rejecting page
+// numbers below 1 or so large that the offset would overflow.
+func (s *userServiceImpl) pageOffset(page, size int) (int, error) {
+	if page < 1 {
+		return 0, fmt.Errorf("%w: page_num must be at least 1", ErrInvalidPagination)
+	}
+	if err := s.validatePageSize(size); err != nil {
+		return 0, err
+	}
+	if page-1 > math.MaxInt/size {
+		return 0, fmt.Errorf("%w: page_num must be at most %d", ErrInvalidPagination, math.MaxInt/size+1)
+	}
+	return (page - 1) * size, nil
 }
 
-func (s *userServiceImpl) GetAllUsers(ctx context.Context, page, size int) ([]model.User, error) {
-	offset := (page - 1) * size
-	return s.repo.GetAllUsers(ctx, offset, size)
+// validatePageSize rejects page sizes outside [1, maxPageSize].
+func (s *userServiceImpl) validatePageSize(size int) error {
+	if size < 1 || size > s.maxPageSize {
+		return fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidPagination, s.maxPageSize)
+	}
+	return nil
 }
 
-// GetUsersByIDs fetches multiple users by their IDs
-func (s *userServiceImpl) GetUsersByIDs(ctx context.Context, ids []uint64) ([]model.User, error) {
+// GetUsersByIDs fetches multiple users by their IDs. Users are returned in
+// the order their IDs were first requested, duplicates are dropped, and IDs
+// with no matching user are reported in NotFound.
+func (s *userServiceImpl) GetUsersByIDs(ctx context.Context, ids []uint64) (model.BatchFetchUsersResponse, error) {
+	resp := model.BatchFetchUsersResponse{Users: make([]model.User, 0), NotFound: make([]uint64, 0)}
 	if len(ids) == 0 {
-		return make([]model.User, 0), nil
+		return resp, nil
 	}
 
-	users, err := s.repo.GetUserByIDs(ctx, ids)
+	unique := make([]uint64, 0, len(ids))
+	seen := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+
+	users, err := s.repo.GetUserByIDs(ctx, unique)
 
 	if err != nil {
-		return nil, err
+		return model.BatchFetchUsersResponse{}, err
 	}
 
-	return users, nil
+	byID := make(map[uint64]model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for _, id := range unique {
+		if user, ok := byID[id]; ok {
+			resp.Users = append(resp.Users, user)
+		} else {
+			resp.NotFound = append(resp.NotFound, id)
+		}
+	}
+
+	return resp, nil
+}
+
+// UpdateUser applies patch to the user with the given ID. When expectedVersion
+// is non-zero the update is rejected unless it matches the current version.
+func (s *userServiceImpl) UpdateUser(ctx context.Context, id uint64, patch model.UserPatch, expectedVersion uint64) (model.User, error) {
+	current, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return model.User{}, ErrVersionConflict
+	}
+
+	updated, err := applyPatch(current, patch)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user, err := s.repo.UpdateUser(ctx, updated, current.Version)
+	if err != nil {
+		return model.User{}, err
+	}
+	s.log.InfoContext(ctx, "user updated", "user_id", user.ID, "version", user.Version)
+	return user, nil
+}
+
+// DeleteUser soft-deletes a user so it can still be restored within the retention window.
+func (s *userServiceImpl) DeleteUser(ctx context.Context, id uint64) error {
+	if err := s.repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	s.log.InfoContext(ctx, "user deleted", "user_id", id)
+	return nil
+}
+
+// RestoreUser undoes a soft delete if it happened within the retention window.
+func (s *userServiceImpl) RestoreUser(ctx context.Context, id uint64) (model.User, error) {
+	user, err := s.repo.RestoreUser(ctx, id, s.retentionCutoff())
+	if err != nil {
+		return model.User{}, err
+	}
+	s.log.InfoContext(ctx, "user restored", "user_id", id)
+	return user, nil
+}
+
+// PurgeDeletedUsers permanently removes users deleted longer ago than the retention window.
+func (s *userServiceImpl) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	purged, err := s.repo.PurgeUsers(ctx, s.retentionCutoff())
+	if err != nil {
+		return 0, err
+	}
+	s.log.InfoContext(ctx, "deleted users purged", "purged", purged)
+	return purged, nil
+}
+
+// retentionCutoff is the oldest deletion timestamp that is still restorable.
+func (s *userServiceImpl) retentionCutoff() int64 {
+	return time.Now().Add(-s.retention).UnixMicro()
 }
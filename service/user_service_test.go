@@ -3,7 +3,11 @@ package service_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 	"user-service/mocks"
 	"user-service/model"
 	"user-service/service"
@@ -133,25 +137,79 @@ func TestUserService_GetAllUsers(t *testing.T) {
 		size      int
 		mockFn    func()
 		wantUsers []model.User
+		wantTotal int64
+		wantNext  bool
+		wantPrev  bool
 		wantError bool
 	}{
 		{
-			name: "success",
+			name: "success with more pages",
 			page: 2,
-			size: 3,
+			size: 2,
 			mockFn: func() {
 				mockRepo.EXPECT().
-					GetAllUsers(ctx, 3, 3).
+					GetAllUsers(ctx, 2, 3).
 					Return([]model.User{
 						{ID: 4, Name: "D"},
-						{ID: 5, Name: "E"},
+						{ID: 3, Name: "C"},
+						{ID: 2, Name: "B"},
 					}, nil)
+				mockRepo.EXPECT().CountUsers(ctx).Return(int64(5), nil)
 			},
 			wantUsers: []model.User{
 				{ID: 4, Name: "D"},
-				{ID: 5, Name: "E"},
+				{ID: 3, Name: "C"},
 			},
-			wantError: false,
+			wantTotal: 5,
+			wantNext:  true,
+			wantPrev:  true,
+		},
+		{
+			name: "last page",
+			page: 1,
+			size: 3,
+			mockFn: func() {
+				mockRepo.EXPECT().
+					GetAllUsers(ctx, 0, 4).
+					Return([]model.User{
+						{ID: 2, Name: "B"},
+						{ID: 1, Name: "A"},
+					}, nil)
+				mockRepo.EXPECT().CountUsers(ctx).Return(int64(2), nil)
+			},
+			wantUsers: []model.User{
+				{ID: 2, Name: "B"},
+				{ID: 1, Name: "A"},
+			},
+			wantTotal: 2,
+		},
+		{
+			name:      "page below one",
+			page:      0,
+			size:      10,
+			mockFn:    func() {},
+			wantError: true,
+		},
+		{
+			name:      "negative page size",
+			page:      1,
+			size:      -5,
+			mockFn:    func() {},
+			wantError: true,
+		},
+		{
+			name:      "page size above max",
+			page:      1,
+			size:      service.DefaultMaxPageSize + 1,
+			mockFn:    func() {},
+			wantError: true,
+		},
+		{
+			name:      "offset overflows",
+			page:      math.MaxInt/10 + 2,
+			size:      10,
+			mockFn:    func() {},
+			wantError: true,
 		},
 		{
 			name: "repo error",
@@ -159,7 +217,7 @@ func TestUserService_GetAllUsers(t *testing.T) {
 			size: 2,
 			mockFn: func() {
 				mockRepo.EXPECT().
-					GetAllUsers(ctx, 0, 2).
+					GetAllUsers(ctx, 0, 3).
 					Return(nil, errors.New("repo failure"))
 			},
 			wantUsers: nil,
@@ -170,17 +228,86 @@ func TestUserService_GetAllUsers(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFn()
-			users, err := svc.GetAllUsers(ctx, tt.page, tt.size)
+			page, err := svc.GetAllUsers(ctx, tt.page, tt.size)
 			if tt.wantError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
 			}
-			assert.Equal(t, tt.wantUsers, users)
+			assert.Equal(t, tt.wantUsers, page.Users)
+			assert.Equal(t, tt.wantTotal, page.Total)
+			assert.Equal(t, tt.wantNext, page.HasNext)
+			assert.Equal(t, tt.wantNext, page.NextCursor != "")
+			assert.Equal(t, tt.wantPrev, page.PrevCursor != "")
 		})
 	}
 }
 
+func TestUserService_ListUsersByCursor(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithCursorSecret([]byte("secret")))
+
+	mockRepo.EXPECT().
+		GetAllUsers(ctx, 0, 3).
+		Return([]model.User{
+			{ID: 5, Name: "E", CreatedAt: 50},
+			{ID: 4, Name: "D", CreatedAt: 40},
+			{ID: 3, Name: "C", CreatedAt: 30},
+		}, nil)
+	mockRepo.EXPECT().CountUsers(ctx).Return(int64(4), nil).AnyTimes()
+	first, err := svc.GetAllUsers(ctx, 1, 2)
+	assert.NoError(t, err)
+
+	t.Run("next page", func(t *testing.T) {
+		mockRepo.EXPECT().
+			GetUsersByCursor(ctx, model.UserCursor{CreatedAt: 40, ID: 4}, 3).
+			Return([]model.User{
+				{ID: 3, Name: "C", CreatedAt: 30},
+				{ID: 2, Name: "B", CreatedAt: 20},
+			}, nil)
+
+		page, err := svc.ListUsersByCursor(ctx, first.NextCursor, 2)
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 2)
+		assert.Empty(t, page.NextCursor)
+		assert.NotEmpty(t, page.PrevCursor)
+
+		mockRepo.EXPECT().
+			GetUsersByCursor(ctx, model.UserCursor{CreatedAt: 30, ID: 3, Before: true}, 3).
+			Return([]model.User{
+				{ID: 5, Name: "E", CreatedAt: 50},
+				{ID: 4, Name: "D", CreatedAt: 40},
+			}, nil)
+
+		prev, err := svc.ListUsersByCursor(ctx, page.PrevCursor, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, first.Users, prev.Users)
+		assert.Empty(t, prev.PrevCursor)
+		assert.NotEmpty(t, prev.NextCursor)
+	})
+
+	t.Run("invalid page size", func(t *testing.T) {
+		_, err := svc.ListUsersByCursor(ctx, first.NextCursor, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidPagination)
+	})
+
+	t.Run("tampered cursor", func(t *testing.T) {
+		tampered := "eyJjIjo5OTksImkiOjk5OX0" + first.NextCursor[strings.Index(first.NextCursor, "."):]
+		_, err := svc.ListUsersByCursor(ctx, tampered, 2)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+
+	t.Run("cursor from another key", func(t *testing.T) {
+		other := service.NewUserService(mockRepo, service.WithCursorSecret([]byte("other")))
+		_, err := other.ListUsersByCursor(ctx, first.NextCursor, 2)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+}
+
 func TestUserService_GetUsersByIDs(t *testing.T) {
 	ctx := context.Background()
 	ctrl := gomock.NewController(t)
@@ -190,11 +317,12 @@ func TestUserService_GetUsersByIDs(t *testing.T) {
 	svc := service.NewUserService(mockRepo)
 
 	tests := []struct {
-		name      string
-		inputIDs  []uint64
-		mockFn    func()
-		wantUsers []model.User
-		wantErr   bool
+		name         string
+		inputIDs     []uint64
+		mockFn       func()
+		wantUsers    []model.User
+		wantNotFound []uint64
+		wantErr      bool
 	}{
 		{
 			name:     "success - multiple users found",
@@ -211,14 +339,36 @@ func TestUserService_GetUsersByIDs(t *testing.T) {
 				{ID: 1, Name: "Alice"},
 				{ID: 2, Name: "Bob"},
 			},
-			wantErr: false,
+			wantNotFound: []uint64{},
+			wantErr:      false,
 		},
 		{
-			name:      "success - empty input",
-			inputIDs:  []uint64{},
-			mockFn:    func() {}, // no repo call expected
-			wantUsers: []model.User{},
-			wantErr:   false,
+			name:     "success - request order, duplicates and missing IDs",
+			inputIDs: []uint64{3, 1, 404, 3, 2, 404},
+			mockFn: func() {
+				mockRepo.EXPECT().
+					GetUserByIDs(ctx, []uint64{3, 1, 404, 2}).
+					Return([]model.User{
+						{ID: 1, Name: "Alice"},
+						{ID: 2, Name: "Bob"},
+						{ID: 3, Name: "Carol"},
+					}, nil)
+			},
+			wantUsers: []model.User{
+				{ID: 3, Name: "Carol"},
+				{ID: 1, Name: "Alice"},
+				{ID: 2, Name: "Bob"},
+			},
+			wantNotFound: []uint64{404},
+			wantErr:      false,
+		},
+		{
+			name:         "success - empty input",
+			inputIDs:     []uint64{},
+			mockFn:       func() {}, // no repo call expected
+			wantUsers:    []model.User{},
+			wantNotFound: []uint64{},
+			wantErr:      false,
 		},
 		{
 			name:     "error from repo",
@@ -237,13 +387,480 @@ func TestUserService_GetUsersByIDs(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFn()
 
-			users, err := svc.GetUsersByIDs(ctx, tt.inputIDs)
+			resp, err := svc.GetUsersByIDs(ctx, tt.inputIDs)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantUsers, resp.Users)
+				assert.Equal(t, tt.wantNotFound, resp.NotFound)
+			}
+		})
+	}
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo)
+
+	current := model.User{ID: 1, Name: "Alice", CreatedAt: 10, UpdatedAt: 10, Version: 3}
+
+	tests := []struct {
+		name            string
+		patch           model.UserPatch
+		expectedVersion uint64
+		mockFn          func()
+		wantUser        model.User
+		wantErr         error
+		wantField       string
+	}{
+		{
+			name:            "merge patch",
+			patch:           model.UserPatch{Format: model.MergePatch, Body: []byte(`{"name":"Alicia"}`)},
+			expectedVersion: 3,
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+				mockRepo.EXPECT().
+					UpdateUser(ctx, model.User{ID: 1, Name: "Alicia", CreatedAt: 10, UpdatedAt: 10, Version: 3}, uint64(3)).
+					Return(model.User{ID: 1, Name: "Alicia", CreatedAt: 10, UpdatedAt: 20, Version: 4}, nil)
+			},
+			wantUser: model.User{ID: 1, Name: "Alicia", CreatedAt: 10, UpdatedAt: 20, Version: 4},
+		},
+		{
+			name: "json patch with test op",
+			patch: model.UserPatch{Format: model.JSONPatch, Body: []byte(`[
+				{"op":"test","path":"/version","value":3},
+				{"op":"replace","path":"/name","value":"Ally"}
+			]`)},
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+				mockRepo.EXPECT().
+					UpdateUser(ctx, model.User{ID: 1, Name: "Ally", CreatedAt: 10, UpdatedAt: 10, Version: 3}, uint64(3)).
+					Return(model.User{ID: 1, Name: "Ally", CreatedAt: 10, UpdatedAt: 20, Version: 4}, nil)
+			},
+			wantUser: model.User{ID: 1, Name: "Ally", CreatedAt: 10, UpdatedAt: 20, Version: 4},
+		},
+		{
+			name:            "stale if-match",
+			patch:           model.UserPatch{Format: model.MergePatch, Body: []byte(`{"name":"Alicia"}`)},
+			expectedVersion: 2,
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+			},
+			wantErr: service.ErrVersionConflict,
+		},
+		{
+			name:  "read-only field",
+			patch: model.UserPatch{Format: model.MergePatch, Body: []byte(`{"id":7}`)},
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+			},
+			wantErr: service.ErrInvalidPatch,
+		},
+		{
+			name:  "remove name",
+			patch: model.UserPatch{Format: model.JSONPatch, Body: []byte(`[{"op":"remove","path":"/name"}]`)},
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+			},
+			wantErr: service.ErrInvalidPatch,
+		},
+		{
+			name:  "name too long",
+			patch: model.UserPatch{Format: model.MergePatch, Body: []byte(`{"name":"` + strings.Repeat("a", service.MaxNameLength+1) + `"}`)},
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(current, nil)
+			},
+			wantErr:   service.ErrInvalidPatch,
+			wantField: "name",
+		},
+		{
+			name:  "user not found",
+			patch: model.UserPatch{Format: model.MergePatch, Body: []byte(`{"name":"Alicia"}`)},
+			mockFn: func() {
+				mockRepo.EXPECT().GetUser(ctx, uint64(1)).Return(model.User{}, service.ErrUserNotFound)
+			},
+			wantErr: service.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFn()
+			user, err := svc.UpdateUser(ctx, 1, tt.patch, tt.expectedVersion)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				if tt.wantField != "" {
+					var fieldErr *service.FieldError
+					if assert.ErrorAs(t, err, &fieldErr) {
+						assert.Equal(t, tt.wantField, fieldErr.Field)
+					}
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUser, user)
+		})
+	}
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo)
+
+	tests := []struct {
+		name    string
+		input   uint64
+		mockFn  func()
+		wantErr error
+	}{
+		{
+			name:  "success",
+			input: 1,
+			mockFn: func() {
+				mockRepo.EXPECT().DeleteUser(ctx, uint64(1)).Return(nil)
+			},
+		},
+		{
+			name:  "not found",
+			input: 999,
+			mockFn: func() {
+				mockRepo.EXPECT().DeleteUser(ctx, uint64(999)).Return(service.ErrUserNotFound)
+			},
+			wantErr: service.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFn()
+			err := svc.DeleteUser(ctx, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithRetention(time.Hour))
+
+	before := time.Now().Add(-time.Hour).UnixMicro()
+	mockRepo.EXPECT().
+		RestoreUser(ctx, uint64(1), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id uint64, deletedSince int64) (model.User, error) {
+			assert.GreaterOrEqual(t, deletedSince, before)
+			assert.LessOrEqual(t, deletedSince, time.Now().Add(-time.Hour).UnixMicro())
+			return model.User{ID: id, Name: "Alice"}, nil
+		})
+
+	user, err := svc.RestoreUser(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{ID: 1, Name: "Alice"}, user)
+}
+
+func TestUserService_PurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo)
+
+	tests := []struct {
+		name       string
+		mockFn     func()
+		wantPurged int64
+		wantErr    bool
+	}{
+		{
+			name: "success",
+			mockFn: func() {
+				mockRepo.EXPECT().PurgeUsers(ctx, gomock.Any()).Return(int64(3), nil)
+			},
+			wantPurged: 3,
+		},
+		{
+			name: "repo error",
+			mockFn: func() {
+				mockRepo.EXPECT().PurgeUsers(ctx, gomock.Any()).Return(int64(0), errors.New("db error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFn()
+			purged, err := svc.PurgeDeletedUsers(ctx)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.wantUsers, users)
 			}
+			assert.Equal(t, tt.wantPurged, purged)
+		})
+	}
+}
+
+func TestUserService_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo)
+
+	tests := []struct {
+		name        string
+		query       string
+		mode        model.SearchMode
+		page        int
+		size        int
+		mockFn      func()
+		wantMatches []model.UserMatch
+		wantHasNext bool
+		wantErr     error
+	}{
+		{
+			name:  "success with more pages",
+			query: "jo",
+			mode:  model.SearchModePrefix,
+			page:  2,
+			size:  1,
+			mockFn: func() {
+				mockRepo.EXPECT().
+					SearchUsers(ctx, "jo", 1, 1).
+					Return([]model.UserMatch{{User: model.User{ID: 2, Name: "Johnny"}, Score: 1.5}}, int64(3), nil)
+			},
+			wantMatches: []model.UserMatch{{User: model.User{ID: 2, Name: "Johnny"}, Score: 1.5}},
+			wantHasNext: true,
+		},
+		{
+			name:  "last page",
+			query: "jo",
+			page:  1,
+			size:  10,
+			mockFn: func() {
+				mockRepo.EXPECT().
+					SearchUsers(ctx, "jo", 0, 10).
+					Return([]model.UserMatch{{User: model.User{ID: 1, Name: "John"}, Score: 2}}, int64(1), nil)
+			},
+			wantMatches: []model.UserMatch{{User: model.User{ID: 1, Name: "John"}, Score: 2}},
+		},
+		{
+			name:  "fuzzy mode",
+			query: "jon doe",
+			mode:  model.SearchModeFuzzy,
+			page:  1,
+			size:  10,
+			mockFn: func() {
+				mockRepo.EXPECT().
+					FuzzySearchUsers(ctx, "jon doe", service.DefaultFuzzyThreshold, 0, 10).
+					Return([]model.UserMatch{{User: model.User{ID: 1, Name: "John Doe"}, Score: 0.55}}, int64(1), nil)
+			},
+			wantMatches: []model.UserMatch{{User: model.User{ID: 1, Name: "John Doe"}, Score: 0.55}},
+		},
+		{
+			name:    "unknown mode",
+			query:   "jo",
+			mode:    "regex",
+			page:    1,
+			size:    10,
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidQuery,
+		},
+		{
+			name:    "blank query",
+			query:   "   ",
+			page:    1,
+			size:    10,
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidQuery,
+		},
+		{
+			name:    "invalid page",
+			query:   "jo",
+			page:    0,
+			size:    10,
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidPagination,
+		},
+		{
+			name:    "offset overflows",
+			query:   "jo",
+			page:    math.MaxInt,
+			size:    10,
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidPagination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFn()
+			page, err := svc.SearchUsers(ctx, tt.query, tt.mode, tt.page, tt.size)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatches, page.Matches)
+			assert.Equal(t, tt.wantHasNext, page.HasNext)
+		})
+	}
+}
+
+func TestUserService_CreateUsers(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	svc := service.NewUserService(mockRepo, service.WithMaxBulkSize(3))
+
+	alice := model.User{ID: 1, Name: "Alice"}
+	bob := model.User{ID: 2, Name: "Bob"}
+
+	tests := []struct {
+		name        string
+		names       []string
+		mode        model.BulkMode
+		mockFn      func()
+		wantResults []model.BulkCreateResult
+		wantErr     error
+	}{
+		{
+			name:  "atomic success",
+			names: []string{"Alice", "Bob"},
+			mode:  model.BulkModeAtomic,
+			mockFn: func() {
+				mockRepo.EXPECT().CreateUsers(ctx, []string{"Alice", "Bob"}).Return([]model.User{alice, bob}, nil)
+			},
+			wantResults: []model.BulkCreateResult{
+				{Index: 0, Status: model.BulkItemCreated, User: &alice},
+				{Index: 1, Status: model.BulkItemCreated, User: &bob},
+			},
+		},
+		{
+			name:   "atomic with invalid item",
+			names:  []string{"Alice", "  "},
+			mode:   model.BulkModeAtomic,
+			mockFn: func() {},
+			wantResults: []model.BulkCreateResult{
+				{Index: 0, Status: model.BulkItemSkipped},
+				{Index: 1, Status: model.BulkItemFailed, Error: "name is required"},
+			},
+			wantErr: service.ErrInvalidUser,
+		},
+		{
+			name:  "partial with invalid item",
+			names: []string{"", "Bob", strings.Repeat("x", service.MaxNameLength+1)},
+			mode:  model.BulkModePartial,
+			mockFn: func() {
+				mockRepo.EXPECT().CreateUsers(ctx, []string{"Bob"}).Return([]model.User{bob}, nil)
+			},
+			wantResults: []model.BulkCreateResult{
+				{Index: 0, Status: model.BulkItemFailed, Error: "name is required"},
+				{Index: 1, Status: model.BulkItemCreated, User: &bob},
+				{Index: 2, Status: model.BulkItemFailed, Error: "name must be at most 255 characters"},
+			},
+		},
+		{
+			name:   "partial with nothing valid",
+			names:  []string{""},
+			mode:   model.BulkModePartial,
+			mockFn: func() {},
+			wantResults: []model.BulkCreateResult{
+				{Index: 0, Status: model.BulkItemFailed, Error: "name is required"},
+			},
+		},
+		{
+			name:  "repo error",
+			names: []string{"Alice"},
+			mode:  model.BulkModePartial,
+			mockFn: func() {
+				mockRepo.EXPECT().CreateUsers(ctx, []string{"Alice"}).Return(nil, errors.New("db error"))
+			},
+			wantErr: errors.New("db error"),
+		},
+		{
+			name:    "too many users",
+			names:   []string{"A", "B", "C", "D"},
+			mode:    model.BulkModeAtomic,
+			mockFn:  func() {},
+			wantErr: service.ErrBatchTooLarge,
+		},
+		{
+			name:    "empty request",
+			names:   []string{},
+			mode:    model.BulkModeAtomic,
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidUser,
+		},
+		{
+			name:    "unknown mode",
+			names:   []string{"Alice"},
+			mode:    "sometimes",
+			mockFn:  func() {},
+			wantErr: service.ErrInvalidUser,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFn()
+			results, err := svc.CreateUsers(ctx, tt.names, tt.mode)
+			switch {
+			case tt.wantErr == nil:
+				assert.NoError(t, err)
+			case errors.Is(tt.wantErr, service.ErrInvalidUser), errors.Is(tt.wantErr, service.ErrBatchTooLarge):
+				assert.ErrorIs(t, err, tt.wantErr)
+			default:
+				assert.EqualError(t, err, tt.wantErr.Error())
+			}
+			assert.Equal(t, tt.wantResults, results)
+		})
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"invalid pagination", service.ErrInvalidPagination, service.ErrValidation},
+		{"invalid query", service.ErrInvalidQuery, service.ErrValidation},
+		{"invalid user", service.ErrInvalidUser, service.ErrValidation},
+		{"invalid cursor", service.ErrInvalidCursor, service.ErrValidation},
+		{"invalid patch", service.ErrInvalidPatch, service.ErrValidation},
+		{"batch too large", service.ErrBatchTooLarge, service.ErrValidation},
+		{"user not found", service.ErrUserNotFound, service.ErrNotFound},
+		{"version conflict", service.ErrVersionConflict, service.ErrConflict},
+		{"user not deleted", service.ErrUserNotDeleted, service.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.err, tt.kind)
+			assert.ErrorIs(t, fmt.Errorf("%w: details", tt.err), tt.kind)
+			assert.NotErrorIs(t, tt.err, service.ErrUnavailable)
 		})
 	}
 }
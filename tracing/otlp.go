@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// FileExporter writes each span as one line of OTLP JSON, the format of an
+// OTLP/HTTP ExportTraceServiceRequest, so the output can be read by an
+// OpenTelemetry collector's file receiver or inspected by hand.
+type FileExporter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	closer   io.Closer
+	resource otlpResource
+	scope    otlpScope
+}
+
+// NewFileExporter returns an exporter writing to w on behalf of serviceName.
+func NewFileExporter(w io.Writer, serviceName string) *FileExporter {
+	return &FileExporter{
+		enc: json.NewEncoder(w),
+		resource: otlpResource{Attributes: []otlpKeyValue{
+			otlpAttr(String("service.name", serviceName)),
+		}},
+		scope: otlpScope{Name: serviceName},
+	}
+}
+
+// OpenFileExporter returns an exporter appending to the file at path, or
+// writing to standard output if path is "stdout".
+func OpenFileExporter(path, serviceName string) (*FileExporter, error) {
+	if path == "stdout" {
+		return NewFileExporter(os.Stdout, serviceName), nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	e := NewFileExporter(f, serviceName)
+	e.closer = f
+	return e, nil
+}
+
+// Export writes d as one line.
+func (e *FileExporter) Export(d SpanData) error {
+	span := otlpSpan{
+		TraceID:           d.TraceID.String(),
+		SpanID:            d.SpanID.String(),
+		Name:              d.Name,
+		Kind:              int(d.Kind),
+		StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+		Attributes:        make([]otlpKeyValue, len(d.Attrs)),
+	}
+	if d.Parent.IsValid() {
+		span.ParentSpanID = d.Parent.String()
+	}
+	for i, a := range d.Attrs {
+		span.Attributes[i] = otlpAttr(a)
+	}
+	if d.Err != "" {
+		span.Status = &otlpStatus{Code: otlpStatusError, Message: d.Err}
+	}
+
+	req := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: e.scope, Spans: []otlpSpan{span}}},
+	}}}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(req)
+}
+
+// Close closes the underlying file, if the exporter opened one.
+func (e *FileExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// The types below mirror the JSON encoding of the OTLP trace protobufs.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+const otlpStatusError = 2
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func otlpAttr(a Attr) otlpKeyValue {
+	kv := otlpKeyValue{Key: a.Key}
+	switch v := a.Value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &s
+	case float64:
+		kv.Value.DoubleValue = &v
+	}
+	return kv
+}
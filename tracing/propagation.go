@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader carries the W3C trace context between services.
+const TraceparentHeader = "traceparent"
+
+// flagSampled is the traceparent flag saying the caller records the trace.
+const flagSampled = 0x01
+
+// ParseTraceparent parses a traceparent header value such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	// Version ff is forbidden and version 00 has exactly four fields. Later
+	// versions may append fields, which are ignored.
+	var v [1]byte
+	if !decodeLowerHex(v[:], version) || v[0] == 0xff || (v[0] == 0 && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], traceID) || !decodeLowerHex(sc.SpanID[:], spanID) {
+		return SpanContext{}, false
+	}
+	var f [1]byte
+	if !decodeLowerHex(f[:], flags) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = f[0]&flagSampled != 0
+	return sc, sc.IsValid()
+}
+
+// decodeLowerHex decodes s into dst, requiring lowercase hex of exactly the
+// right length.
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// FormatTraceparent formats sc as a traceparent header value.
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Extract returns a context that continues the trace named by the traceparent
+// header in h, if it has a valid one.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	if sc, ok := ParseTraceparent(h.Get(TraceparentHeader)); ok {
+		return ContextWithRemoteParent(ctx, sc)
+	}
+	return ctx
+}
+
+// Inject sets the traceparent header in h for a call made on behalf of the
+// current span in ctx. It does nothing if ctx carries no trace.
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		h.Set(TraceparentHeader, FormatTraceparent(sc))
+	}
+}
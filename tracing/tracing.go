@@ -0,0 +1,256 @@
+// Package tracing records spans for requests and the work done on their
+// behalf, continues traces started by other services through the W3C
+// traceparent header, and exports finished spans as OTLP JSON.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether id is not all zeros.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether id is not all zeros.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether sc identifies a span.
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// SpanKind says what role a span plays, using the OTLP numbering.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attr is a span attribute. Value is a string, bool, int64 or float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{key, value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr { return Attr{key, int64(value)} }
+
+// Int64 returns an integer attribute.
+func Int64(key string, value int64) Attr { return Attr{key, value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attr { return Attr{key, value} }
+
+// SpanData is a finished span as handed to an Exporter.
+type SpanData struct {
+	SpanContext
+	Parent     SpanID
+	Name       string
+	Kind       SpanKind
+	Start, End time.Time
+	Attrs      []Attr
+	// Err is the error recorded on the span, if any.
+	Err string
+}
+
+// Exporter ships finished, sampled spans somewhere.
+type Exporter interface {
+	Export(SpanData) error
+}
+
+// Option customizes a Tracer.
+type Option func(*Tracer)
+
+// WithSampleRatio sets the fraction, between 0 and 1, of new traces that are
+// recorded. Traces continued from another service follow its decision.
+func WithSampleRatio(ratio float64) Option {
+	return func(t *Tracer) {
+		t.ratio = ratio
+	}
+}
+
+// Tracer starts spans and exports them when they end.
+type Tracer struct {
+	exporter Exporter
+	ratio    float64
+}
+
+// NewTracer returns a tracer exporting to exporter that records every trace
+// unless WithSampleRatio says otherwise.
+func NewTracer(exporter Exporter, opts ...Option) *Tracer {
+	t := &Tracer{exporter: exporter, ratio: 1}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext returns the span stored in ctx by Start, or nil. All Span
+// methods are safe to call on nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithRemoteParent returns a context under which the next span started
+// continues the trace of a span in another process.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span in ctx,
+// or of the remote parent if no span was started locally.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.data.SpanContext
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// StartOption customizes a span when it starts.
+type StartOption func(*SpanData)
+
+// WithKind sets the span kind; spans are internal by default.
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) {
+		d.Kind = kind
+	}
+}
+
+// WithAttributes sets attributes on the span as it starts.
+func WithAttributes(attrs ...Attr) StartOption {
+	return func(d *SpanData) {
+		d.Attrs = append(d.Attrs, attrs...)
+	}
+}
+
+// Start begins a span that is a child of the span or remote parent in ctx and
+// returns a context carrying it. The span must be ended with End.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	s := &Span{tracer: t, data: SpanData{Name: name, Kind: KindInternal, Start: time.Now()}}
+	for _, opt := range opts {
+		opt(&s.data)
+	}
+
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		s.data.TraceID = parent.TraceID
+		s.data.Parent = parent.SpanID
+		s.data.Sampled = parent.Sampled
+	} else {
+		s.data.TraceID = newTraceID()
+		s.data.Sampled = rand.Float64() < t.ratio
+	}
+	s.data.SpanID = newSpanID()
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
+
+// Span is an operation being traced. It is safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the identity of s, or the zero value for a nil span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttributes adds attributes to s.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+}
+
+// RecordError marks s as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err.Error()
+}
+
+// End finishes s and exports it if its trace is sampled. Calls after the
+// first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if !data.Sampled || s.tracer.exporter == nil {
+		return
+	}
+	if err := s.tracer.exporter.Export(data); err != nil {
+		slog.Warn("export span", "span", data.Name, "error", err)
+	}
+}
@@ -0,0 +1,152 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"user-service/tracing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder keeps exported spans in memory.
+type recorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *recorder) Export(d tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, d)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantOK  bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version with extra field", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what", true, true},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what", false, false},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"short span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := tracing.ParseTraceparent(tt.value)
+			assert.Equal(t, tt.wantOK, ok)
+			if ok {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+				assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+				assert.Equal(t, tt.sampled, sc.Sampled)
+			}
+		})
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	rec := &recorder{}
+	tracer := tracing.NewTracer(rec)
+
+	h := http.Header{}
+	h.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := tracing.Extract(context.Background(), h)
+
+	ctx, server := tracer.Start(ctx, "server", tracing.WithKind(tracing.KindServer))
+	_, child := tracer.Start(ctx, "child", tracing.WithAttributes(tracing.String("k", "v")))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End()
+	server.End()
+
+	out := http.Header{}
+	tracing.Inject(ctx, out)
+	assert.Equal(t, tracing.FormatTraceparent(server.SpanContext()), out.Get(tracing.TraceparentHeader))
+
+	if assert.Len(t, rec.spans, 2) {
+		c, s := rec.spans[0], rec.spans[1]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", s.Parent.String())
+		assert.Equal(t, tracing.KindServer, s.Kind)
+		assert.Equal(t, s.TraceID, c.TraceID)
+		assert.Equal(t, s.SpanID, c.Parent)
+		assert.Equal(t, tracing.KindInternal, c.Kind)
+		assert.Equal(t, []tracing.Attr{tracing.String("k", "v")}, c.Attrs)
+		assert.Equal(t, "boom", c.Err)
+	}
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	rec := &recorder{}
+	tracer := tracing.NewTracer(rec, tracing.WithSampleRatio(0))
+
+	_, span := tracer.Start(context.Background(), "new trace")
+	span.End()
+	assert.Empty(t, rec.spans, "new traces are dropped at ratio 0")
+
+	sc, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span = tracer.Start(tracing.ContextWithRemoteParent(context.Background(), sc), "continued")
+	span.End()
+	assert.Len(t, rec.spans, 1, "the caller's sampling decision wins")
+}
+
+func TestSpan_Nil(t *testing.T) {
+	var span *tracing.Span
+	span.SetAttributes(tracing.Bool("k", true))
+	span.RecordError(errors.New("boom"))
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+	assert.Nil(t, tracing.SpanFromContext(context.Background()))
+}
+
+func TestFileExporter(t *testing.T) {
+	var out bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewFileExporter(&out, "user-service"))
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, span := tracer.Start(ctx, "GET /users/:id", tracing.WithKind(tracing.KindServer),
+		tracing.WithAttributes(tracing.Int("http.response.status_code", 500), tracing.Bool("ok", false)))
+	span.RecordError(errors.New("boom"))
+	span.End()
+
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]any `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []map[string]any `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &req))
+	assert.Equal(t, []map[string]any{
+		{"key": "service.name", "value": map[string]any{"stringValue": "user-service"}},
+	}, req.ResourceSpans[0].Resource.Attributes)
+
+	got := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, span.SpanContext().TraceID.String(), got["traceId"])
+	assert.Equal(t, span.SpanContext().SpanID.String(), got["spanId"])
+	assert.Equal(t, parent.SpanContext().SpanID.String(), got["parentSpanId"])
+	assert.Equal(t, "GET /users/:id", got["name"])
+	assert.Equal(t, float64(tracing.KindServer), got["kind"])
+	assert.Equal(t, []any{
+		map[string]any{"key": "http.response.status_code", "value": map[string]any{"intValue": "500"}},
+		map[string]any{"key": "ok", "value": map[string]any{"boolValue": false}},
+	}, got["attributes"])
+	assert.Equal(t, map[string]any{"code": float64(2), "message": "boom"}, got["status"])
+}